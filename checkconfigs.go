@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type CheckConfigsCmd struct {
+	configs.SpecOptions `ask:"."`
+	Fork                string `ask:"--fork" help:"Fork of the genesis state. Defaults to the latest fork at the genesis epoch in the consensus-layer config"`
+
+	Eth1Config          string           `ask:"--eth1-config" help:"Path to config JSON for eth1"`
+	Eth1BlockTimestamp  common.Timestamp `ask:"--timestamp" help:"Eth1 block timestamp"`
+	EthMatchGenesisTime bool             `ask:"--eth1-match-genesis-time" help:"Use execution-layer genesis time as beacon genesis time. Overrides other genesis time settings."`
+
+	ShadowForkEth1RPC   string `ask:"--shadow-fork-eth1-rpc" help:"Fetch the Eth1 block from the eth1 node for the shadow fork"`
+	ShadowForkBlock     string `ask:"--shadow-fork-block" help:"Block to fetch from the eth1 node for the shadow fork: a block number, block hash, or 'latest', 'safe' or 'finalized' tag"`
+	ShadowForkBlockFile string `ask:"--shadow-fork-block-file" help:"Fetch the Eth1 block from a file for the shadow fork(overwrites RPC option)"`
+}
+
+func (g *CheckConfigsCmd) Help() string {
+	return "Check that the execution-layer genesis config matches the consensus-layer config: fork times, deposit contract, chain ID and genesis delay"
+}
+
+func (g *CheckConfigsCmd) Default() {
+	g.SpecOptions.Default()
+	g.Eth1Config = "engine_genesis.json"
+	g.Eth1BlockTimestamp = common.Timestamp(time.Now().Unix())
+}
+
+func (g *CheckConfigsCmd) Run(ctx context.Context, args ...string) error {
+	spec, err := g.SpecOptions.Spec()
+	if err != nil {
+		return err
+	}
+	fork := genesis.GenesisFork(spec)
+	if g.Fork != "" {
+		fork, err = genesis.ForkByName(g.Fork)
+		if err != nil {
+			return err
+		}
+	}
+	eth1Genesis, err := genesis.LoadEth1GenesisConf(g.Eth1Config)
+	if err != nil {
+		return err
+	}
+	eth1Block, _, err := genesis.LoadEth1Block(ctx, g.ShadowForkBlockFile, g.ShadowForkEth1RPC, g.ShadowForkBlock, eth1Genesis)
+	if err != nil {
+		return err
+	}
+	if g.ShadowForkBlockFile != "" || g.ShadowForkEth1RPC != "" {
+		fmt.Printf("using shadow-fork block %d (%s)\n", eth1Block.NumberU64(), eth1Block.Hash())
+	}
+	genesisTime := genesis.SelectEth1Timestamp(spec, eth1Genesis, g.EthMatchGenesisTime, g.Eth1BlockTimestamp) + spec.GENESIS_DELAY
+	fmt.Printf("checking %s genesis at %d (%s)\n", fork.Name, genesisTime, time.Unix(int64(genesisTime), 0).String())
+
+	errs := genesis.CheckConfigs(spec, fork, eth1Genesis, eth1Block, genesisTime)
+	for _, err := range errs {
+		fmt.Printf("mismatch: %v\n", err)
+	}
+	for _, unchecked := range genesis.UncheckedConfigs(spec) {
+		fmt.Printf("not checked: %s\n", unchecked)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("found %d config mismatches", len(errs))
+	}
+	fmt.Println("configs match")
+	return nil
+}
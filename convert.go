@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type ConvertCmd struct {
+	configs.SpecOptions `ask:"."`
+	Input               string `ask:"--input" help:"Path to the beacon state to convert: SSZ (.ssz), JSON (.json) or YAML (.yaml, .yml)"`
+	Output              string `ask:"--output" help:"Path to write the converted state to: SSZ (.ssz), JSON (.json) or YAML (.yaml, .yml)"`
+	Fork                string `ask:"--fork" help:"Fork of the state. Detected from the fork version of the state and the config if not set"`
+}
+
+func (g *ConvertCmd) Help() string {
+	return "Convert a beacon state between SSZ, and JSON or YAML in the Beacon API encoding, e.g. to diff or hand-edit a genesis state"
+}
+
+func (g *ConvertCmd) Default() {
+	g.SpecOptions.Default()
+	g.Input = "genesis.ssz"
+	g.Output = "genesis.json"
+	g.Fork = ""
+}
+
+func (g *ConvertCmd) Run(ctx context.Context, args ...string) error {
+	spec, err := g.SpecOptions.Spec()
+	if err != nil {
+		return err
+	}
+	inputFormat, err := stateFormat(g.Input)
+	if err != nil {
+		return err
+	}
+	outputFormat, err := stateFormat(g.Output)
+	if err != nil {
+		return err
+	}
+	stateData, fork, err := readStateData(spec, g.Input, inputFormat, g.Fork)
+	if err != nil {
+		return err
+	}
+	if outputFormat == "ssz" {
+		if err := os.WriteFile(g.Output, stateData, 0644); err != nil {
+			return fmt.Errorf("failed to write state: %w", err)
+		}
+	} else if err := writeStateText(g.Output, outputFormat, fork.StateType(spec), stateData); err != nil {
+		return err
+	}
+	fmt.Printf("converted %s state %s to %s\n", fork.Name, g.Input, g.Output)
+	return nil
+}
+
+// stateFormat returns the format of a state file by its extension: ssz, json or yaml.
+func stateFormat(path string) (string, error) {
+	switch filepath.Ext(path) {
+	case ".ssz":
+		return "ssz", nil
+	case ".json":
+		return "json", nil
+	case ".yaml", ".yml":
+		return "yaml", nil
+	default:
+		return "", fmt.Errorf("unknown state format of %s, expected a .ssz, .json, .yaml or .yml file", path)
+	}
+}
+
+// readStateData reads a state file, and returns the SSZ data of the state.
+func readStateData(spec *common.Spec, path string, format string, forkName string) ([]byte, *genesis.Fork, error) {
+	if format != "ssz" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read state: %w", err)
+		}
+		defer f.Close()
+		return genesis.ReadState(spec, f, forkName)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	var fork *genesis.Fork
+	if forkName != "" {
+		fork, err = genesis.ForkByName(forkName)
+	} else {
+		fork, err = genesis.DetectFork(spec, data)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, fork, nil
+}
+
+// writeStateText writes the SSZ data of a state as JSON or YAML.
+func writeStateText(path string, format string, typ view.TypeDef, stateData []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if format == "yaml" {
+		err = genesis.WriteYAML(f, typ, stateData)
+	} else {
+		err = genesis.WriteJSON(f, typ, stateData)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write state %s: %w", format, err)
+	}
+	return f.Close()
+}
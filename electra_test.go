@@ -23,7 +23,7 @@ import (
 func TestElectra(t *testing.T) {
 	elGenesis := core.Genesis{
 		Config: &params.ChainConfig{
-			ChainID:                 big.NewInt(123),
+			ChainID:                 big.NewInt(5), // DEPOSIT_CHAIN_ID of the minimal config
 			HomesteadBlock:          big.NewInt(0),
 			DAOForkBlock:            nil,
 			DAOForkSupport:          false,
@@ -97,9 +97,10 @@ func TestElectra(t *testing.T) {
 	}
 	outPath := filepath.Join(testResourceDir, "out.ssz")
 	tranchesPath := filepath.Join(testResourceDir, "tranches")
-	c := &ElectraGenesisCmd{
+	c := &ForkGenesisCmd{
+		Fork: "electra",
 		SpecOptions: configs.SpecOptions{
-			Config:          "minimal",
+			Config:          writeTestConfig(t, testResourceDir, "electra"),
 			Phase0Preset:    "minimal",
 			AltairPreset:    "minimal",
 			BellatrixPreset: "minimal",
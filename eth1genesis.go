@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type Eth1GenesisCmd struct {
+	configs.SpecOptions `ask:"."`
+
+	Eth1Template        string           `ask:"--eth1-template" help:"Path to a genesis.json template with the alloc and other execution-layer settings"`
+	DepositContractCode string           `ask:"--deposit-contract-code" help:"Path to the hex-encoded deployed bytecode of the deposit contract. Optional if the template has the deposit contract"`
+	Eth1BlockTimestamp  common.Timestamp `ask:"--timestamp" help:"Eth1 genesis timestamp, if there is no MIN_GENESIS_TIME"`
+	OutputPath          string           `ask:"--output" help:"Output path for the execution-layer genesis.json"`
+}
+
+func (g *Eth1GenesisCmd) Help() string {
+	return "Create the execution-layer genesis.json from the consensus-layer config and a template, with matching fork times, deposit contract and system contracts"
+}
+
+func (g *Eth1GenesisCmd) Default() {
+	g.SpecOptions.Default()
+	g.Eth1Template = "genesis_template.json"
+	g.Eth1BlockTimestamp = common.Timestamp(time.Now().Unix())
+	g.OutputPath = "genesis.json"
+}
+
+func (g *Eth1GenesisCmd) Run(ctx context.Context, args ...string) error {
+	spec, err := g.SpecOptions.Spec()
+	if err != nil {
+		return err
+	}
+	eth1Genesis, err := generateEth1Genesis(spec, g.Eth1Template, g.DepositContractCode, g.Eth1BlockTimestamp)
+	if err != nil {
+		return err
+	}
+	if err := writeEth1Genesis(g.OutputPath, eth1Genesis); err != nil {
+		return err
+	}
+	fmt.Printf("wrote execution-layer genesis to %s\n", g.OutputPath)
+	return nil
+}
+
+func generateEth1Genesis(spec *common.Spec, templatePath string, depositContractCodePath string, eth1BlockTimestamp common.Timestamp) (*core.Genesis, error) {
+	template, err := genesis.LoadEth1GenesisConf(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	var depositContractCode []byte
+	if depositContractCodePath != "" {
+		depositContractCode, err = genesis.LoadDepositContractCode(depositContractCodePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	eth1Timestamp := genesis.SelectEth1Timestamp(spec, nil, false, eth1BlockTimestamp)
+	return genesis.GenerateEth1Genesis(spec, template, eth1Timestamp, depositContractCode)
+}
+
+func writeEth1Genesis(outPath string, eth1Genesis *core.Genesis) error {
+	data, err := json.MarshalIndent(eth1Genesis, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode execution-layer genesis: %w", err)
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
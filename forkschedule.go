@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type ForkScheduleCmd struct {
+	configs.SpecOptions   `ask:"."`
+	StatePath             string      `ask:"--state" help:"Path to the SSZ genesis state, of any fork, to take the genesis validators root from"`
+	GenesisValidatorsRoot common.Root `ask:"--genesis-validators-root" help:"Genesis validators root of the network. Used instead of --state if set"`
+	Format                string      `ask:"--format" help:"Output format: text, yaml or json"`
+}
+
+func (g *ForkScheduleCmd) Help() string {
+	return "Print the fork schedule of a network, with the fork digest, BPO forks and eth2 ENR field of each fork, for bootnode and p2p debugging"
+}
+
+func (g *ForkScheduleCmd) Default() {
+	g.SpecOptions.Default()
+	g.StatePath = "genesis.ssz"
+	g.GenesisValidatorsRoot = common.Root{}
+	g.Format = "text"
+}
+
+func (g *ForkScheduleCmd) Run(ctx context.Context, args ...string) error {
+	spec, err := g.SpecOptions.Spec()
+	if err != nil {
+		return err
+	}
+	genesisValidatorsRoot := g.GenesisValidatorsRoot
+	if genesisValidatorsRoot == (common.Root{}) {
+		if g.StatePath == "" {
+			return errors.New("no --state or --genesis-validators-root to compute the fork digests with")
+		}
+		stateData, err := os.ReadFile(g.StatePath)
+		if err != nil {
+			return fmt.Errorf("failed to read state: %w", err)
+		}
+		state, _, err := genesis.DecodeState(spec, stateData)
+		if err != nil {
+			return err
+		}
+		if genesisValidatorsRoot, err = state.GenesisValidatorsRoot(); err != nil {
+			return err
+		}
+	}
+	schedule, err := forkDigestSchedule(&g.SpecOptions, spec, genesisValidatorsRoot)
+	if err != nil {
+		return err
+	}
+	return writeForkSchedule(os.Stdout, g.Format, schedule)
+}
+
+// forkDigestSchedule returns the fork schedule with digests, with the BPO forks of the BLOB_SCHEDULE of the config.
+func forkDigestSchedule(opts *configs.SpecOptions, spec *common.Spec, genesisValidatorsRoot common.Root) ([]genesis.ForkDigestEntry, error) {
+	configYAML, err := specConfigYAML(opts, spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	blobSchedule, err := genesis.ParseBlobSchedule(configYAML)
+	if err != nil {
+		return nil, err
+	}
+	return genesis.ForkDigestSchedule(spec, blobSchedule, genesisValidatorsRoot), nil
+}
+
+// writeForkScheduleFile writes the fork schedule as YAML if the path ends with .yaml or .yml, JSON otherwise.
+func writeForkScheduleFile(path string, schedule []genesis.ForkDigestEntry) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	format := "json"
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		format = "yaml"
+	}
+	if err := writeForkSchedule(f, format, schedule); err != nil {
+		return fmt.Errorf("failed to write fork schedule: %w", err)
+	}
+	return f.Close()
+}
+
+func writeForkSchedule(w io.Writer, format string, schedule []genesis.ForkDigestEntry) error {
+	switch format {
+	case "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "fork\tversion\tepoch\tmax_blobs\tdigest\tnext_fork_version\tnext_fork_epoch\tnext_fork_digest\teth2")
+		for _, e := range schedule {
+			maxBlobs := "-"
+			if e.MaxBlobsPerBlock != 0 {
+				maxBlobs = fmt.Sprintf("%d", e.MaxBlobsPerBlock)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				e.Name, e.Version, e.Epoch, maxBlobs, e.Digest,
+				e.NextForkVersion, e.NextForkEpoch, e.NextForkDigest, e.ENRForkID)
+		}
+		return tw.Flush()
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(schedule); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(schedule)
+	default:
+		return fmt.Errorf("unknown output format %q, expected text, yaml or json", format)
+	}
+}
@@ -0,0 +1,87 @@
+package fulu
+
+import (
+	"fmt"
+
+	. "github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+)
+
+// The Fulu BeaconState is the Electra BeaconState, with the proposer lookahead appended (EIP-7917).
+// zrnt does not implement Fulu yet, so the Electra state view is extended here,
+// reusing all the Electra field accessors, since the field indices are unchanged.
+const _proposerLookahead = 37
+
+func ProposerLookaheadLength(spec *common.Spec) uint64 {
+	return uint64(spec.MIN_SEED_LOOKAHEAD+1) * uint64(spec.SLOTS_PER_EPOCH)
+}
+
+func ProposerLookaheadType(spec *common.Spec) *BasicVectorTypeDef {
+	return BasicVectorType(common.ValidatorIndexType, ProposerLookaheadLength(spec))
+}
+
+func BeaconStateType(spec *common.Spec) *ContainerTypeDef {
+	electraFields := electra.BeaconStateType(spec).Fields
+	fields := make([]FieldDef, 0, len(electraFields)+1)
+	fields = append(fields, electraFields...)
+	// [New in Fulu:EIP7917]
+	fields = append(fields, FieldDef{Name: "proposer_lookahead", Type: ProposerLookaheadType(spec)})
+	return ContainerType("BeaconState", fields)
+}
+
+// To load a state:
+//
+//	state, err := fulu.AsBeaconStateView(fulu.BeaconStateType(spec).Deserialize(codec.NewDecodingReader(reader, size)))
+func AsBeaconStateView(v View, err error) (*BeaconStateView, error) {
+	c, err := AsContainer(v, err)
+	return &BeaconStateView{&electra.BeaconStateView{ContainerView: c}}, err
+}
+
+type BeaconStateView struct {
+	*electra.BeaconStateView
+}
+
+var _ common.BeaconState = (*BeaconStateView)(nil)
+
+func NewBeaconStateView(spec *common.Spec) *BeaconStateView {
+	return &BeaconStateView{&electra.BeaconStateView{ContainerView: BeaconStateType(spec).New()}}
+}
+
+func (state *BeaconStateView) ProposerLookahead() ([]common.ValidatorIndex, error) {
+	v, err := AsBasicVector(state.Get(_proposerLookahead))
+	if err != nil {
+		return nil, err
+	}
+	length := v.Length()
+	out := make([]common.ValidatorIndex, length)
+	for i := uint64(0); i < length; i++ {
+		elem, err := common.AsValidatorIndex(v.Get(i))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = elem
+	}
+	return out, nil
+}
+
+func (state *BeaconStateView) SetProposerLookahead(indices []common.ValidatorIndex) error {
+	v, err := AsBasicVector(state.Get(_proposerLookahead))
+	if err != nil {
+		return err
+	}
+	if uint64(len(indices)) != v.Length() {
+		return fmt.Errorf("expected %d proposer lookahead indices, got %d", v.Length(), len(indices))
+	}
+	for i, index := range indices {
+		if err := v.Set(uint64(i), Uint64View(index)); err != nil {
+			return err
+		}
+	}
+	return state.Set(_proposerLookahead, v)
+}
+
+func (state *BeaconStateView) CopyState() (common.BeaconState, error) {
+	return AsBeaconStateView(state.ContainerView.Copy())
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/eth2-testnet-genesis/fulu"
+)
+
+func TestFulu(t *testing.T) {
+	elGenesis := testEth1Genesis()
+	elGenesisData, err := json.Marshal(elGenesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResourceDir := t.TempDir()
+	elGenesisPath := filepath.Join(testResourceDir, "genesis.json")
+	if err := os.WriteFile(elGenesisPath, elGenesisData, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mnemonicsPath := filepath.Join(testResourceDir, "mnemonics.yaml")
+	mnemonicsData := []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 1000
+`)
+	if err := os.WriteFile(mnemonicsPath, mnemonicsData, 0755); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(testResourceDir, "out.ssz")
+	tranchesPath := filepath.Join(testResourceDir, "tranches")
+	c := &ForkGenesisCmd{
+		Fork: "fulu",
+		SpecOptions: configs.SpecOptions{
+			Config:          writeTestConfig(t, testResourceDir, "fulu"),
+			Phase0Preset:    "minimal",
+			AltairPreset:    "minimal",
+			BellatrixPreset: "minimal",
+			CapellaPreset:   "minimal",
+			DenebPreset:     "minimal",
+			ElectraPreset:   "minimal",
+		},
+		Eth1Config:            elGenesisPath,
+		Eth1BlockHash:         common.Root{},
+		Eth1BlockTimestamp:    0,
+		EthMatchGenesisTime:   true,
+		MnemonicsSrcFilePath:  mnemonicsPath,
+		ValidatorsSrcFilePath: "",
+		StateOutputPath:       outPath,
+		TranchesDir:           tranchesPath,
+		EthWithdrawalAddress:  common.Eth1Address{},
+		ShadowForkEth1RPC:     "",
+		ShadowForkBlockFile:   "",
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stateData, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := c.SpecOptions.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := codec.NewDecodingReader(bytes.NewReader(stateData), uint64(len(stateData)))
+	state, err := fulu.AsBeaconStateView(fulu.BeaconStateType(spec).Deserialize(dec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fork, err := state.Fork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork.CurrentVersion != spec.FULU_FORK_VERSION || fork.PreviousVersion != spec.ELECTRA_FORK_VERSION {
+		t.Fatalf("unexpected fork: %+v", fork)
+	}
+	header, err := state.LatestExecutionPayloadHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadRoot, err := header.BlockHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadHash := gethcommon.Hash(payloadRoot)
+	elGenesisBlock := elGenesis.ToBlock()
+	elHash := elGenesisBlock.Hash()
+	if elHash != payloadHash {
+		t.Fatalf("el hash mismatch:\n%s <- from SSZ state\n%s <- from EL genesis", payloadHash, elHash)
+	}
+	lookahead, err := state.ProposerLookahead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(lookahead)) != fulu.ProposerLookaheadLength(spec) {
+		t.Fatalf("unexpected proposer lookahead length: %d", len(lookahead))
+	}
+	var nonZero bool
+	for _, index := range lookahead {
+		if index >= 1000 {
+			t.Fatalf("proposer index %d out of range", index)
+		}
+		if index != 0 {
+			nonZero = true
+		}
+	}
+	if !nonZero {
+		t.Fatal("proposer lookahead was not initialized")
+	}
+	t.Logf("successfully created genesis beacon state, with block hash %s", elHash)
+}
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func() error) []byte {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+	err = fn()
+	os.Stdout = stdout
+	_ = w.Close()
+	data := <-out
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestFuluForkDigest(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeTestConfig(t, dir, "fulu")
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("BLOB_SCHEDULE:\n  - EPOCH: 0\n    MAX_BLOBS_PER_BLOCK: 12\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	specOptions := configs.SpecOptions{
+		Config:          configPath,
+		Phase0Preset:    "minimal",
+		AltairPreset:    "minimal",
+		BellatrixPreset: "minimal",
+		CapellaPreset:   "minimal",
+		DenebPreset:     "minimal",
+		ElectraPreset:   "minimal",
+	}
+	statePath := filepath.Join(dir, "genesis.ssz")
+	c := &ForkGenesisCmd{
+		Fork:               "fulu",
+		SpecOptions:        specOptions,
+		Eth1BlockTimestamp: 1000,
+		InteropValidators:  64,
+		StateOutputPath:    statePath,
+		TranchesDir:        filepath.Join(dir, "tranches"),
+	}
+	captureStdout(t, func() error { return c.Run(context.Background()) })
+
+	// inspect and fork-schedule print the same digest for the genesis fork
+	var details struct {
+		GenesisForkDigest common.ForkDigest `json:"genesis_fork_digest"`
+	}
+	inspect := &InspectCmd{SpecOptions: specOptions, StatePath: statePath, Format: "json"}
+	if err := json.Unmarshal(captureStdout(t, func() error { return inspect.Run(context.Background()) }), &details); err != nil {
+		t.Fatal(err)
+	}
+	var schedule []struct {
+		Name   string            `json:"name"`
+		Digest common.ForkDigest `json:"digest"`
+	}
+	forkSchedule := &ForkScheduleCmd{SpecOptions: specOptions, StatePath: statePath, Format: "json"}
+	if err := json.Unmarshal(captureStdout(t, func() error { return forkSchedule.Run(context.Background()) }), &schedule); err != nil {
+		t.Fatal(err)
+	}
+	if len(schedule) == 0 || schedule[len(schedule)-1].Name != "fulu" {
+		t.Fatalf("expected fulu to be the last fork, got %v", schedule)
+	}
+	if d := schedule[len(schedule)-1].Digest; d != details.GenesisForkDigest {
+		t.Fatalf("inspect prints fork digest %s, fork-schedule prints %s", details.GenesisForkDigest, d)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type ForkGenesisCmd struct {
+	configs.SpecOptions `ask:"."`
+	Fork                string `ask:"--fork" help:"Fork of the genesis state"`
+
+	Eth1Config        string `ask:"--eth1-config" help:"Path to config JSON for eth1. No transition yet if empty. Only used before the merge if explicitly set."`
+	Eth1ConfigChanged bool   `changed:"eth1-config"`
+
+	Eth1Template        string `ask:"--eth1-template" help:"Generate the execution-layer config from this genesis.json template first, and write it to the --eth1-config path. See the eth1-genesis sub-command."`
+	DepositContractCode string `ask:"--deposit-contract-code" help:"With --eth1-template: path to the hex-encoded deployed bytecode of the deposit contract"`
+
+	Eth1BlockHash      common.Root      `ask:"--eth1-block" help:"If there is no execution-layer block: Eth1 block hash to put into state."`
+	Eth1BlockTimestamp common.Timestamp `ask:"--timestamp" help:"Eth1 block timestamp"`
+
+	EthMatchGenesisTime bool `ask:"--eth1-match-genesis-time" help:"Use execution-layer genesis time as beacon genesis time. Overrides other genesis time settings."`
+
+	SkipConfigChecks bool `ask:"--skip-config-checks" help:"Do not check the execution-layer config against the consensus-layer config, see the check-configs sub-command"`
+
+	InteropValidators     uint64   `ask:"--interop-validators" help:"Number of validators with insecure interop keys (sha256 of the index), for CI and client interop tests. Added before all other validators"`
+	MnemonicsSrcFilePath  string   `ask:"--mnemonics" help:"File with YAML of key sources"`
+	MnemonicsChanged      bool     `changed:"mnemonics"`
+	ValidatorsSrcFilePath string   `ask:"--additional-validators" help:"File with list of additional validators"`
+	KeystoresDir          string   `ask:"--keystores-dir" help:"Directory with EIP-2335 keystores to add validators from (lighthouse, teku, nimbus or prysm import layout)"`
+	KeystoresSecretsDir   string   `ask:"--keystores-secrets-dir" help:"Optional directory with the passwords of the --keystores-dir keystores, to decrypt and verify them"`
+	DepositDataPaths      []string `ask:"--deposit-data" help:"Comma-separated deposit_data JSON files to add validators from. Deposits with an invalid signature or root are rejected."`
+	StateOutputPath       string   `ask:"--state-output" help:"Output path for state file"`
+	StateJSONOutputPath   string   `ask:"--state-json-output" help:"Optional output path for the state as JSON, in the Beacon API encoding"`
+	StateYAMLOutputPath   string   `ask:"--state-yaml-output" help:"Optional output path for the state as YAML, with the same values as the JSON"`
+	OutputDir             string   `ask:"--output-dir" help:"Optional directory to write the network config bundle to, in the eth-clients testnet layout: config.yaml, genesis.ssz, genesis.json, deposit contract block, genesis validators root and parsedConsensusGenesis.json. Replaces --state-output"`
+	MetadataOutputPath    string   `ask:"--metadata-output" help:"Optional output path for a JSON file with metadata of the genesis state, like the execution-layer block"`
+	DepositSnapshotOutput string   `ask:"--deposit-snapshot-output" help:"Optional output path for the EIP-4881 deposit tree snapshot of the genesis state, SSZ if the path ends with .ssz, JSON otherwise"`
+	ForkScheduleOutput    string   `ask:"--fork-schedule-output" help:"Optional output path for the fork schedule with the fork digests and eth2 ENR fields of the genesis validators root, YAML if the path ends with .yaml or .yml, JSON otherwise"`
+	TranchesDir           string   `ask:"--tranches-dir" help:"Directory to dump lists of pubkeys of each tranche in"`
+	PubkeyCacheDir        string   `ask:"--pubkey-cache-dir" help:"Optional directory to cache the pubkeys derived from the mnemonics in, to only derive new pubkeys in repeated builds. Secret keys are never cached"`
+	StrictValidators      bool     `ask:"--strict-validators" help:"Check for duplicate pubkeys across all validator sources and for invalid BLS pubkeys, and fail on rejected deposits"`
+
+	ExportKeystoresDir          string `ask:"--export-keystores-dir" help:"Optional directory to write EIP-2335 keystores and secrets of the mnemonic validators to, with a sub-directory per tranche"`
+	ExportKeystoresLayout       string `ask:"--export-keystores-layout" help:"Consensus client directory layout of the exported keystores: lighthouse, lodestar, nimbus, prysm or teku"`
+	ExportKeystoresKDF          string `ask:"--export-keystores-kdf" help:"Key derivation function of the exported keystores: scrypt or pbkdf2"`
+	ExportKeystoresPasswordFile string `ask:"--export-keystores-password-file" help:"File with the password of all exported keystores. Random passwords if not set"`
+
+	EthWithdrawalAddress common.Eth1Address `ask:"--eth1-withdrawal-address" help:"Eth1 Withdrawal to set for the genesis validator set"`
+
+	KeystoresWithdrawalCredentials common.Root `ask:"--keystores-withdrawal-credentials" help:"Withdrawal credentials of the --keystores-dir validators. BLS withdrawal credentials of the validator pubkey if not set"`
+	KeystoresBalance               common.Gwei `ask:"--keystores-balance" help:"Balance of the --keystores-dir validators, in Gwei. MAX_EFFECTIVE_BALANCE if not set"`
+	ShadowForkEth1RPC              string      `ask:"--shadow-fork-eth1-rpc" help:"Fetch the Eth1 block from the eth1 node for the shadow fork"`
+	ShadowForkBlock                string      `ask:"--shadow-fork-block" help:"Block to fetch from the eth1 node for the shadow fork: a block number, block hash, or 'latest', 'safe' or 'finalized' tag"`
+	ShadowForkBlockFile            string      `ask:"--shadow-fork-block-file" help:"Fetch the Eth1 block from a file for the shadow fork(overwrites RPC option)"`
+
+	DepositContractRPC      string `ask:"--deposit-contract-rpc" help:"Eth1 node to call get_deposit_root and get_deposit_count of the deposit contract on, at the Eth1 block, to continue the deposits of a network with a live deposit contract"`
+	DepositContractLogsFile string `ask:"--deposit-contract-logs" help:"JSON file with the DepositEvent logs of the deposit contract (eth_getLogs result), to rebuild the deposit tree from instead of --deposit-contract-rpc"`
+
+	DepositRequestsStartIndex string `ask:"--deposit-requests-start-index" help:"Electra and later: deposit_requests_start_index of the state, a number, or 'unset' to process Eth1 bridge deposits until the first deposit request"`
+	PendingQueuesFilePath     string `ask:"--pending-queues" help:"Electra and later: JSON or YAML file with pending_deposits, pending_partial_withdrawals and pending_consolidations to put in the state"`
+
+	// regenesis is set by the regenesis sub-command
+	regenesis *genesis.RegenesisSrc
+}
+
+func (g *ForkGenesisCmd) Help() string {
+	if g.Fork != "" {
+		return fmt.Sprintf("Create genesis state for %s beacon chain. Alias for 'genesis --fork=%s'", g.Fork, g.Fork)
+	}
+	return fmt.Sprintf("Create genesis state for any fork (%s), from execution-layer and consensus-layer configs", strings.Join(genesis.ForkNames(), ", "))
+}
+
+// Default does not change the fork, so the per-fork sub-commands can preset it.
+func (g *ForkGenesisCmd) Default() {
+	g.SpecOptions.Default()
+	g.Eth1Config = "engine_genesis.json"
+
+	g.Eth1BlockHash = common.Root{}
+	g.Eth1BlockTimestamp = common.Timestamp(time.Now().Unix())
+
+	g.MnemonicsSrcFilePath = "mnemonics.yaml"
+	g.ValidatorsSrcFilePath = ""
+	g.StateOutputPath = "genesis.ssz"
+	g.TranchesDir = "tranches"
+	g.ExportKeystoresDir = ""
+	g.ExportKeystoresLayout = "lighthouse"
+	g.ExportKeystoresKDF = "scrypt"
+	g.ExportKeystoresPasswordFile = ""
+	g.ShadowForkEth1RPC = ""
+	g.ShadowForkBlock = ""
+	g.ShadowForkBlockFile = ""
+	g.DepositContractRPC = ""
+	g.DepositContractLogsFile = ""
+	g.DepositRequestsStartIndex = "0"
+	g.PendingQueuesFilePath = ""
+}
+
+func (g *ForkGenesisCmd) Run(ctx context.Context, args ...string) error {
+	spec, eth1Genesis, res, err := g.build(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("done preparing state, serializing SSZ now...")
+	if g.OutputDir != "" {
+		configYAML, err := specConfigYAML(&g.SpecOptions, spec)
+		if err != nil {
+			return err
+		}
+		if err := genesis.WriteBundle(spec, g.OutputDir, &genesis.Bundle{
+			ConfigYAML:  configYAML,
+			Eth1Genesis: eth1Genesis,
+			Result:      res,
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("wrote network config bundle to %s\n", g.OutputDir)
+	} else if err := writeState(g.StateOutputPath, res.State); err != nil {
+		return err
+	}
+	if g.StateJSONOutputPath != "" || g.StateYAMLOutputPath != "" {
+		var stateData bytes.Buffer
+		if err := res.State.Serialize(codec.NewEncodingWriter(&stateData)); err != nil {
+			return err
+		}
+		for format, path := range map[string]string{"json": g.StateJSONOutputPath, "yaml": g.StateYAMLOutputPath} {
+			if path == "" {
+				continue
+			}
+			if err := writeStateText(path, format, res.Fork.StateType(spec), stateData.Bytes()); err != nil {
+				return err
+			}
+			fmt.Printf("wrote state %s to %s\n", format, path)
+		}
+	}
+	if g.MetadataOutputPath != "" {
+		if err := writeMetadata(g.MetadataOutputPath, res.Metadata()); err != nil {
+			return err
+		}
+	}
+	if g.DepositSnapshotOutput != "" {
+		snapshot, err := res.DepositTreeSnapshot()
+		if err != nil {
+			return err
+		}
+		if err := genesis.WriteDepositTreeSnapshot(g.DepositSnapshotOutput, snapshot); err != nil {
+			return err
+		}
+		fmt.Printf("wrote deposit tree snapshot with %d deposits to %s\n", snapshot.DepositCount, g.DepositSnapshotOutput)
+	}
+	genesisValidatorsRoot, err := res.State.GenesisValidatorsRoot()
+	if err != nil {
+		return err
+	}
+	schedule, err := forkDigestSchedule(&g.SpecOptions, spec, genesisValidatorsRoot)
+	if err != nil {
+		return err
+	}
+	fmt.Println("fork schedule:")
+	if err := writeForkSchedule(os.Stdout, "text", schedule); err != nil {
+		return err
+	}
+	if g.ForkScheduleOutput != "" {
+		if err := writeForkScheduleFile(g.ForkScheduleOutput, schedule); err != nil {
+			return err
+		}
+		fmt.Printf("wrote fork schedule to %s\n", g.ForkScheduleOutput)
+	}
+	fmt.Println("done!")
+	return nil
+}
+
+// build creates the genesis state of the flags, without writing it.
+func (g *ForkGenesisCmd) build(ctx context.Context) (*common.Spec, *core.Genesis, *genesis.Result, error) {
+	fmt.Printf("zrnt version: %s\n", eth2.VERSION)
+
+	fork, err := genesis.ForkByName(g.Fork)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	fmt.Printf("creating %s genesis state\n", fork.Name)
+
+	spec, err := g.SpecOptions.Spec()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var depositRequestsStartIndex uint64
+	switch g.DepositRequestsStartIndex {
+	case "", "0":
+	case "unset":
+		depositRequestsStartIndex = genesis.UNSET_DEPOSIT_REQUESTS_START_INDEX
+	default:
+		depositRequestsStartIndex, err = strconv.ParseUint(g.DepositRequestsStartIndex, 10, 64)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid deposit requests start index %q: %w", g.DepositRequestsStartIndex, err)
+		}
+	}
+
+	var eth1Genesis *core.Genesis
+	if g.Eth1Template != "" {
+		eth1Genesis, err = generateEth1Genesis(spec, g.Eth1Template, g.DepositContractCode, g.Eth1BlockTimestamp)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err := writeEth1Genesis(g.Eth1Config, eth1Genesis); err != nil {
+			return nil, nil, nil, err
+		}
+		fmt.Printf("wrote execution-layer genesis to %s\n", g.Eth1Config)
+	} else if g.Eth1Config != "" && (fork.SetPayloadHeader != nil || g.Eth1ConfigChanged) {
+		// Before the merge there is no execution-layer genesis, unless explicitly configured.
+		eth1Genesis, err = genesis.LoadEth1GenesisConf(g.Eth1Config)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	mnemonicsSrcFilePath := g.MnemonicsSrcFilePath
+	if !g.MnemonicsChanged {
+		// The default mnemonics file is optional, other validator sources may be used instead.
+		if _, err := os.Stat(mnemonicsSrcFilePath); errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("no %s, not generating validators from mnemonics\n", mnemonicsSrcFilePath)
+			mnemonicsSrcFilePath = ""
+		}
+	}
+
+	var exportKeystores *genesis.KeystoresExport
+	if g.ExportKeystoresDir != "" {
+		exportKeystores = &genesis.KeystoresExport{
+			Dir:          g.ExportKeystoresDir,
+			Layout:       g.ExportKeystoresLayout,
+			KDF:          g.ExportKeystoresKDF,
+			PasswordFile: g.ExportKeystoresPasswordFile,
+		}
+	}
+
+	res, err := genesis.Build(ctx, &genesis.Options{
+		Spec:                    spec,
+		Fork:                    fork.Name,
+		Log:                     os.Stdout,
+		Eth1Genesis:             eth1Genesis,
+		MatchEth1GenesisTime:    g.EthMatchGenesisTime,
+		SkipConfigChecks:        g.SkipConfigChecks,
+		Eth1BlockHash:           g.Eth1BlockHash,
+		Eth1BlockTimestamp:      g.Eth1BlockTimestamp,
+		ShadowForkEth1RPC:       g.ShadowForkEth1RPC,
+		ShadowForkBlock:         g.ShadowForkBlock,
+		ShadowForkBlockFile:     g.ShadowForkBlockFile,
+		DepositContractRPC:      g.DepositContractRPC,
+		DepositContractLogsFile: g.DepositContractLogsFile,
+		InteropValidators:       g.InteropValidators,
+		MnemonicsSrcFilePath:    mnemonicsSrcFilePath,
+		ValidatorsSrcFilePath:   g.ValidatorsSrcFilePath,
+		DepositDataPaths:        g.DepositDataPaths,
+		KeystoresDir:            g.KeystoresDir,
+		KeystoresSecretsDir:     g.KeystoresSecretsDir,
+
+		KeystoresWithdrawalCredentials: g.KeystoresWithdrawalCredentials,
+		KeystoresBalance:               g.KeystoresBalance,
+		TranchesDir:                    g.TranchesDir,
+		ExportKeystores:                exportKeystores,
+		PubkeyCacheDir:                 g.PubkeyCacheDir,
+		StrictValidators:               g.StrictValidators,
+		EthWithdrawalAddress:           g.EthWithdrawalAddress,
+
+		DepositRequestsStartIndex: depositRequestsStartIndex,
+		PendingQueuesFilePath:     g.PendingQueuesFilePath,
+		Regenesis:                 g.regenesis,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, p := range res.RejectedDeposits {
+		fmt.Printf("rejected deposit: %s\n", p)
+	}
+	if res.Churn != nil {
+		fmt.Printf("earliest exit epoch: %d\n", res.Churn.EarliestExitEpoch)
+		fmt.Printf("earliest consolidation epoch: %d\n", res.Churn.EarliestConsolidationEpoch)
+		fmt.Printf("exit balance to consume: %d, consolidation balance to consume: %d\n",
+			res.Churn.ExitBalanceToConsume, res.Churn.ConsolidationBalanceToConsume)
+	}
+	for _, w := range res.Warnings {
+		fmt.Printf("WARNING: %s\n", w)
+	}
+	fmt.Printf("genesis at %d + %d = %d  (%s)\n", res.Eth1Timestamp, spec.GENESIS_DELAY, res.GenesisTime, time.Unix(int64(res.GenesisTime), 0).String())
+	return spec, eth1Genesis, res, nil
+}
+
+// specConfigYAML returns the config.yaml of the spec: the --config file as-is, to keep the fields that zrnt does not know,
+// or else the config of the named preset.
+func specConfigYAML(opts *configs.SpecOptions, spec *common.Spec) ([]byte, error) {
+	switch opts.Config {
+	case "mainnet", "minimal":
+		return yaml.Marshal(&spec.Config)
+	default:
+		return os.ReadFile(opts.Config)
+	}
+}
+
+func writeMetadata(outPath string, m *genesis.Metadata) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
+
+func writeState(outPath string, state common.BeaconState) error {
+	f, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	buf := bufio.NewWriter(f)
+	w := codec.NewEncodingWriter(buf)
+	if err := state.Serialize(w); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
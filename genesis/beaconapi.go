@@ -0,0 +1,164 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// BeaconAPIHandler serves the genesis state over the Beacon API endpoints that clients need to start from it,
+// e.g. with --checkpoint-sync-url or --genesis-state-url:
+//
+//   - /eth/v1/beacon/genesis
+//   - /eth/v2/debug/beacon/states/{state_id}, for the genesis, finalized, justified and head state ids,
+//     the slot and the root of the state, as SSZ or JSON by the Accept header
+//   - /eth/v1/config/spec
+//   - /eth/v1/config/fork_schedule
+//   - /eth/v1/config/deposit_contract
+func BeaconAPIHandler(spec *common.Spec, fork *Fork, state common.BeaconState) (http.Handler, error) {
+	var stateData bytes.Buffer
+	if err := state.Serialize(codec.NewEncodingWriter(&stateData)); err != nil {
+		return nil, fmt.Errorf("failed to serialize state: %w", err)
+	}
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	genesisValidatorsRoot, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		return nil, err
+	}
+	values, err := specValues(spec)
+	if err != nil {
+		return nil, err
+	}
+	api := &beaconAPI{
+		spec:      spec,
+		fork:      fork,
+		stateData: stateData.Bytes(),
+		stateIDs: []string{"genesis", "finalized", "justified", "head",
+			strconv.FormatUint(uint64(slot), 10), state.HashTreeRoot(tree.GetHashFn()).String()},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /eth/v1/beacon/genesis", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIData(w, map[string]any{
+			"genesis_time":            genesisTime,
+			"genesis_validators_root": genesisValidatorsRoot,
+			"genesis_fork_version":    spec.GENESIS_FORK_VERSION,
+		})
+	})
+	mux.HandleFunc("GET /eth/v2/debug/beacon/states/{state_id}", api.serveState)
+	mux.HandleFunc("GET /eth/v1/config/spec", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIData(w, values)
+	})
+	mux.HandleFunc("GET /eth/v1/config/fork_schedule", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIData(w, ForkSchedule(spec))
+	})
+	mux.HandleFunc("GET /eth/v1/config/deposit_contract", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIData(w, map[string]any{
+			"chain_id": spec.DEPOSIT_CHAIN_ID,
+			"address":  spec.DEPOSIT_CONTRACT_ADDRESS,
+		})
+	})
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("%s is not served, only the genesis state and config", r.URL.Path))
+	})
+	return mux, nil
+}
+
+type beaconAPI struct {
+	spec      *common.Spec
+	fork      *Fork
+	stateData []byte
+	// stateIDs are the state ids of the state: it is the genesis state, and the only state.
+	stateIDs []string
+}
+
+func (api *beaconAPI) serveState(w http.ResponseWriter, r *http.Request) {
+	stateID := r.PathValue("state_id")
+	if !slices.Contains(api.stateIDs, stateID) {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("state %s not found, only the genesis state is served", stateID))
+		return
+	}
+	w.Header().Set("Eth-Consensus-Version", api.fork.Name)
+	if prefersSSZ(r) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(api.stateData)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = fmt.Fprintf(w, `{"version":%q,"execution_optimistic":false,"finalized":true,"data":`, api.fork.Name)
+	// The state was serialized from a valid state view, it always transcodes.
+	_ = WriteJSON(w, api.fork.StateType(api.spec), api.stateData)
+	_, _ = w.Write([]byte("}\n"))
+}
+
+// prefersSSZ returns true if the Accept header of the request prefers SSZ over JSON. JSON is the default.
+func prefersSSZ(r *http.Request) bool {
+	sszQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "application/octet-stream":
+			sszQ = max(sszQ, q)
+		case "application/json", "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return sszQ > 0 && sszQ >= jsonQ
+}
+
+func writeAPIData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func writeAPIError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": code, "message": message})
+}
+
+// specValues returns the presets and config of the spec as strings, like the /eth/v1/config/spec Beacon API.
+func specValues(spec *common.Spec) (map[string]string, error) {
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode spec: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode spec: %w", err)
+	}
+	values := make(map[string]string)
+	m := doc.Content[0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if v := m.Content[i+1]; v.Kind == yaml.ScalarNode {
+			values[m.Content[i].Value] = v.Value
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,131 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+)
+
+func TestBeaconAPIHandler(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ELECTRA_FORK_EPOCH = 10
+	spec.FULU_FORK_EPOCH = common.FAR_FUTURE_EPOCH
+	res, err := Build(context.Background(), &Options{
+		Spec:          &spec,
+		Fork:          "deneb",
+		Eth1BlockHash: common.Root{1},
+		Validators:    testValidators(t, &spec, 64),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := BeaconAPIHandler(&spec, res.Fork, res.State)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	get := func(path string, accept string) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+	getData := func(path string, dst any) {
+		resp, body := get(path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", path, resp.StatusCode)
+		}
+		if err := json.Unmarshal(body, &struct{ Data any }{Data: dst}); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+
+	var genesis map[string]string
+	getData("/eth/v1/beacon/genesis", &genesis)
+	if genesis["genesis_time"] != "1578009900" || genesis["genesis_validators_root"] != res.GenesisValidatorsRoot.String() ||
+		genesis["genesis_fork_version"] != "0x00000001" {
+		t.Fatalf("unexpected genesis %v", genesis)
+	}
+	var schedule []map[string]string
+	getData("/eth/v1/config/fork_schedule", &schedule)
+	if len(schedule) != 6 || schedule[5]["previous_version"] != "0x04000001" || schedule[5]["current_version"] != "0x05000001" ||
+		schedule[5]["epoch"] != "10" {
+		t.Fatalf("unexpected fork schedule %v", schedule)
+	}
+	var depositContract map[string]string
+	getData("/eth/v1/config/deposit_contract", &depositContract)
+	if depositContract["chain_id"] != "5" || depositContract["address"] != spec.DEPOSIT_CONTRACT_ADDRESS.String() {
+		t.Fatalf("unexpected deposit contract %v", depositContract)
+	}
+	var values map[string]string
+	getData("/eth/v1/config/spec", &values)
+	if values["SLOTS_PER_EPOCH"] != "8" || values["ELECTRA_FORK_EPOCH"] != "10" || values["DENEB_FORK_VERSION"] != "0x04000001" {
+		t.Fatalf("unexpected spec values %v", values)
+	}
+
+	var stateData bytes.Buffer
+	if err := res.State.Serialize(codec.NewEncodingWriter(&stateData)); err != nil {
+		t.Fatal(err)
+	}
+	stateRoot := res.State.HashTreeRoot(tree.GetHashFn())
+	for _, stateID := range []string{"genesis", "finalized", "0", stateRoot.String()} {
+		resp, body := get("/eth/v2/debug/beacon/states/"+stateID, "application/octet-stream;q=1.0,application/json;q=0.9")
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/octet-stream" ||
+			resp.Header.Get("Eth-Consensus-Version") != "deneb" || !bytes.Equal(body, stateData.Bytes()) {
+			t.Fatalf("unexpected SSZ state response for %s", stateID)
+		}
+	}
+	resp, body := get("/eth/v2/debug/beacon/states/head", "application/json, application/octet-stream;q=0.5")
+	var stateResp struct {
+		Version string          `json:"version"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &stateResp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" || stateResp.Version != "deneb" {
+		t.Fatalf("unexpected JSON state response %s", body[:100])
+	}
+	data, _, err := ReadState(&spec, bytes.NewReader(stateResp.Data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, stateData.Bytes()) {
+		t.Fatal("JSON state does not match")
+	}
+
+	if resp, _ := get("/eth/v2/debug/beacon/states/1", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found for other state, got %d", resp.StatusCode)
+	}
+	if resp, _ := get("/eth/v2/beacon/blocks/head", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found for other endpoint, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,374 @@
+// Package genesis creates beacon-chain genesis states for testnets,
+// with pre-filled validators, for any fork. The eth2-testnet-genesis CLI is a thin wrapper around Build.
+package genesis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// Options configures the creation of a genesis state with Build.
+type Options struct {
+	Spec *common.Spec
+	// Fork name, see ForkNames.
+	Fork string
+	// Log receives the progress of Build, like the loaded validator sources, as lines of text. Discarded if nil.
+	Log io.Writer
+
+	// Eth1Genesis is the execution-layer genesis. Optional before the merge.
+	Eth1Genesis *core.Genesis
+	// MatchEth1GenesisTime uses the execution-layer genesis time as beacon genesis time.
+	// Overrides other genesis time settings.
+	MatchEth1GenesisTime bool
+	// Eth1BlockHash is put into the state if there is no execution-layer block.
+	Eth1BlockHash common.Root
+	// Eth1BlockTimestamp is used as genesis time (before GENESIS_DELAY) if there is no MIN_GENESIS_TIME.
+	Eth1BlockTimestamp common.Timestamp
+
+	// SkipConfigChecks disables the CheckConfigs pre-flight of the execution-layer and consensus-layer configs.
+	SkipConfigChecks bool
+
+	// ShadowForkEth1RPC is an execution-layer RPC to fetch the execution-layer block from for a shadow fork.
+	ShadowForkEth1RPC string
+	// ShadowForkBlock selects the block to fetch from ShadowForkEth1RPC: a block number, block hash,
+	// or "latest", "safe" or "finalized" tag. The latest block if empty.
+	ShadowForkBlock string
+	// ShadowForkBlockFile is a JSON file to read the execution-layer block from for a shadow fork.
+	// Takes precedence over ShadowForkEth1RPC.
+	ShadowForkBlockFile string
+
+	// DepositContractRPC is an execution-layer RPC to read the deposit tree of the deposit contract from,
+	// at the execution-layer block of the genesis state, see FetchDepositContractState.
+	// The deposit tree is empty if there is no RPC and no logs file.
+	DepositContractRPC string
+	// DepositContractLogsFile is a JSON file with the DepositEvent logs of the deposit contract to rebuild
+	// the deposit tree from, see LoadDepositContractLogs.
+	DepositContractLogsFile string
+
+	// InteropValidators is the number of validators with insecure interop keys, added before all other validators.
+	InteropValidators uint64
+	// MnemonicsSrcFilePath is an optional file with YAML of key sources.
+	MnemonicsSrcFilePath string
+	// ValidatorsSrcFilePath is an optional file with a list of validators.
+	ValidatorsSrcFilePath string
+	// KeystoresDir is an optional directory with EIP-2335 keystores to load validators from, see KeystoresSrc.
+	KeystoresDir string
+	// KeystoresSecretsDir is an optional directory with the keystore passwords, to verify the keystores with.
+	KeystoresSecretsDir string
+	// KeystoresWithdrawalCredentials of the keystore validators. BLS credentials of the validator pubkey if zero.
+	KeystoresWithdrawalCredentials common.Root
+	// KeystoresBalance of the keystore validators. MAX_EFFECTIVE_BALANCE if zero.
+	KeystoresBalance common.Gwei
+	// DepositDataPaths are optional deposit_data JSON files to load validators from, see LoadDepositData.
+	DepositDataPaths []string
+	// TranchesDir is the directory to dump lists of pubkeys of each mnemonic tranche in.
+	TranchesDir string
+	// ExportKeystores optionally writes the EIP-2335 keystores of the mnemonic validators.
+	ExportKeystores *KeystoresExport
+	// PubkeyCacheDir optionally caches the pubkeys derived from the mnemonics, see WarmPubkeyCache.
+	PubkeyCacheDir string
+	// EthWithdrawalAddress is the withdrawal address of the mnemonic validators. BLS withdrawal credentials if zero.
+	EthWithdrawalAddress common.Eth1Address
+	// StrictValidators checks for duplicate pubkeys across all validator sources, and for invalid BLS pubkeys,
+	// and makes rejected deposits fatal. See CheckValidators.
+	StrictValidators bool
+	// Regenesis optionally takes the validator registry from an existing state. The validators of all other sources
+	// replace validators of the registry then, instead of being added.
+	Regenesis *RegenesisSrc
+	// Validators are added to the state after the validators from the mnemonics, validators list, keystores and deposit data.
+	Validators []phase0.KickstartValidatorData
+
+	// DepositRequestsStartIndex is the deposit_requests_start_index of Electra and later genesis states.
+	// Zero by default, which disables Eth1 bridge deposits.
+	// Use UNSET_DEPOSIT_REQUESTS_START_INDEX for the spec genesis behavior.
+	DepositRequestsStartIndex uint64
+	// PendingQueuesFilePath is an optional file with Electra pending deposits, partial withdrawals and consolidations,
+	// see LoadPendingQueues.
+	PendingQueuesFilePath string
+}
+
+// Result is the genesis state created by Build, with metadata of how it was created.
+type Result struct {
+	State common.BeaconState
+	Fork  *Fork
+	// Eth1Block is the execution-layer block embedded in the state, nil if there is none.
+	Eth1Block *types.Block
+	// ShadowFork is true if the execution-layer block is from a shadow-forked network, instead of the genesis config.
+	ShadowFork bool
+	// Eth1Timestamp is the genesis time before the GENESIS_DELAY is added.
+	Eth1Timestamp         common.Timestamp
+	GenesisTime           common.Timestamp
+	GenesisValidatorsRoot common.Root
+	ValidatorCount        uint64
+	// DepositContract is the deposit tree that the state continues from, nil if the deposit tree is empty.
+	DepositContract *DepositContractState
+	// RejectedDeposits are the deposit data entries that were skipped. Always empty with StrictValidators.
+	RejectedDeposits []*DepositDataProblem
+	// Churn is the exit and consolidation churn of the state, nil before Electra.
+	Churn *ElectraChurn
+	// Warnings are problems of the genesis state that do not stop Build,
+	// like fewer validators than the MIN_GENESIS_ACTIVE_VALIDATOR_COUNT.
+	Warnings []string
+}
+
+// logWriter returns w, or io.Discard if w is nil, to write progress to.
+// Progress is written from multiple goroutines, so the writes to w are serialized.
+func logWriter(w io.Writer) io.Writer {
+	switch w.(type) {
+	case nil:
+		return io.Discard
+	case *syncWriter:
+		return w
+	}
+	return &syncWriter{w: w}
+}
+
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// SelectEth1Timestamp selects the genesis time before GENESIS_DELAY:
+// the execution-layer genesis time if matchEth1GenesisTime is set, or else the MIN_GENESIS_TIME,
+// or else the given eth1BlockTimestamp.
+func SelectEth1Timestamp(spec *common.Spec, eth1Genesis *core.Genesis, matchEth1GenesisTime bool, eth1BlockTimestamp common.Timestamp) common.Timestamp {
+	if matchEth1GenesisTime && eth1Genesis != nil {
+		return common.Timestamp(eth1Genesis.Timestamp)
+	} else if spec.MIN_GENESIS_TIME != 0 {
+		// Load the genesis timestamp from the CL config, this is better in terms of compatibility for shadowforks
+		return spec.MIN_GENESIS_TIME
+	} else {
+		return eth1BlockTimestamp
+	}
+}
+
+// Build creates a genesis state.
+func Build(ctx context.Context, opts *Options) (*Result, error) {
+	spec := opts.Spec
+	log := logWriter(opts.Log)
+	fork, err := ForkByName(opts.Fork)
+	if err != nil {
+		return nil, err
+	}
+	eth1Genesis := opts.Eth1Genesis
+
+	var pendingQueues *PendingQueues
+	if !fork.AtLeast("electra") {
+		if opts.DepositRequestsStartIndex != 0 || opts.PendingQueuesFilePath != "" {
+			return nil, fmt.Errorf("deposit requests start index and pending queues are not supported before electra")
+		}
+	} else if opts.PendingQueuesFilePath != "" {
+		pendingQueues, err = LoadPendingQueues(opts.PendingQueuesFilePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	beaconGenesisTimestamp := SelectEth1Timestamp(spec, eth1Genesis, opts.MatchEth1GenesisTime, opts.Eth1BlockTimestamp)
+	if !(opts.MatchEth1GenesisTime && eth1Genesis != nil) && spec.MIN_GENESIS_TIME != 0 {
+		fmt.Fprintln(log, "using CL MIN_GENESIS_TIME for genesis timestamp")
+	}
+
+	eth1Block, prevRandaoMix, err := LoadEth1Block(ctx, opts.ShadowForkBlockFile, opts.ShadowForkEth1RPC, opts.ShadowForkBlock, eth1Genesis)
+	if err != nil {
+		return nil, err
+	}
+	isShadowFork := opts.ShadowForkBlockFile != "" || opts.ShadowForkEth1RPC != ""
+	if isShadowFork {
+		fmt.Fprintf(log, "using shadow-fork block %d (%s)\n", eth1Block.NumberU64(), eth1Block.Hash())
+	}
+	if eth1Genesis != nil && !isShadowFork && fork.CheckEth1Genesis != nil {
+		if err := fork.CheckEth1Genesis(eth1Genesis); err != nil {
+			return nil, err
+		}
+	}
+	if eth1Genesis != nil && eth1Block != nil && !opts.SkipConfigChecks {
+		if errs := CheckConfigs(spec, fork, eth1Genesis, eth1Block, beaconGenesisTimestamp+spec.GENESIS_DELAY); len(errs) > 0 {
+			return nil, fmt.Errorf("execution-layer and consensus-layer configs do not match: %w", errors.Join(errs...))
+		}
+		for _, unchecked := range UncheckedConfigs(spec) {
+			fmt.Fprintf(log, "not checked: %s\n", unchecked)
+		}
+	}
+
+	var eth1BlockHash common.Root
+	if eth1Block != nil {
+		eth1BlockHash = common.Root(eth1Block.Hash())
+	} else {
+		if fork.SetPayloadHeader != nil {
+			fmt.Fprintln(log, "no eth1 config found, using eth1 block hash and timestamp, with empty ExecutionPayloadHeader (no PoW->PoS transition yet in execution layer)")
+		}
+		eth1BlockHash = opts.Eth1BlockHash
+	}
+
+	var depositContract *DepositContractState
+	if opts.DepositContractRPC != "" && opts.DepositContractLogsFile != "" {
+		return nil, fmt.Errorf("the deposit contract state can be read from an RPC or from a logs file, not both")
+	} else if opts.DepositContractRPC != "" {
+		if eth1Block == nil {
+			return nil, fmt.Errorf("the deposit contract state is read at the execution-layer block, but there is no execution-layer block")
+		}
+		depositContract, err = FetchDepositContractState(ctx, opts.DepositContractRPC, spec.DEPOSIT_CONTRACT_ADDRESS, eth1BlockHash)
+		if err != nil {
+			return nil, err
+		}
+	} else if opts.DepositContractLogsFile != "" {
+		blockNumber := uint64(math.MaxUint64)
+		if eth1Block != nil {
+			blockNumber = eth1Block.NumberU64()
+		}
+		depositContract, err = LoadDepositContractLogs(opts.DepositContractLogsFile, spec.DEPOSIT_CONTRACT_ADDRESS, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if depositContract != nil {
+		fmt.Fprintf(log, "continuing from %d deposits of deposit contract %s, with deposit root %s\n",
+			depositContract.DepositCount, spec.DEPOSIT_CONTRACT_ADDRESS, depositContract.DepositRoot)
+	}
+
+	if opts.MnemonicsSrcFilePath != "" || opts.InteropValidators > 0 {
+		if err := os.MkdirAll(opts.TranchesDir, 0777); err != nil {
+			return nil, err
+		}
+	}
+
+	validators, origins, rejectedDeposits, err := LoadValidatorKeys(spec, &ValidatorSources{
+		Log:                  log,
+		InteropValidators:    opts.InteropValidators,
+		MnemonicsConfigPath:  opts.MnemonicsSrcFilePath,
+		TranchesDir:          opts.TranchesDir,
+		EthWithdrawalAddress: opts.EthWithdrawalAddress,
+		KeystoresExport:      opts.ExportKeystores,
+		PubkeyCacheDir:       opts.PubkeyCacheDir,
+		ValidatorsListPath:   opts.ValidatorsSrcFilePath,
+		Keystores: &KeystoresSrc{
+			Dir:                   opts.KeystoresDir,
+			SecretsDir:            opts.KeystoresSecretsDir,
+			WithdrawalCredentials: opts.KeystoresWithdrawalCredentials,
+			Balance:               opts.KeystoresBalance,
+		},
+		DepositDataPaths: opts.DepositDataPaths,
+		Fork:             fork,
+		Validators:       opts.Validators,
+		Strict:           opts.StrictValidators,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.StrictValidators {
+		if err := CheckValidators(validators, origins); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(log, "checked %d validators for duplicate and invalid pubkeys\n", len(validators))
+	}
+
+	var warnings []string
+	if depositContract != nil && depositContract.deployBlockErr != nil {
+		warnings = append(warnings, fmt.Sprintf("the deploy block of deposit contract %s is not known: %v. It is not in the network config bundle.",
+			spec.DEPOSIT_CONTRACT_ADDRESS, depositContract.deployBlockErr))
+	}
+	var state common.BeaconState
+	validatorCount := uint64(len(validators))
+	if opts.Regenesis != nil {
+		reg, err := LoadRegistryFile(spec, opts.Regenesis.StateFork, opts.Regenesis.StatePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := reg.Replace(spec, opts.Regenesis.ReplaceIndices, validators); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(log, "loaded %d validators from state at epoch %d, replaced %d validators\n", len(reg.Validators), reg.Epoch, len(validators))
+		validatorCount = uint64(len(reg.Validators))
+		state, err = SetupRegenesisState(spec, fork, beaconGenesisTimestamp, eth1BlockHash, reg)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if validatorCount < uint64(spec.MIN_GENESIS_ACTIVE_VALIDATOR_COUNT) {
+			warnings = append(warnings, fmt.Sprintf("not enough validators for genesis. Key sources sum up to %d total. But need %d.", len(validators), spec.MIN_GENESIS_ACTIVE_VALIDATOR_COUNT))
+		}
+		state, err = SetupState(spec, fork, beaconGenesisTimestamp, eth1BlockHash, validators)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if depositContract != nil {
+		if err := SetDepositContractState(state, depositContract, eth1BlockHash); err != nil {
+			return nil, err
+		}
+	}
+
+	if fork.SetPayloadHeader != nil && eth1Block != nil {
+		if err := fork.SetPayloadHeader(spec, state, eth1Block, prevRandaoMix); err != nil {
+			return nil, err
+		}
+	}
+
+	if fork.PostSetup != nil {
+		if err := fork.PostSetup(spec, state); err != nil {
+			return nil, err
+		}
+	}
+
+	var churn *ElectraChurn
+	if fork.AtLeast("electra") {
+		st := electraStateView(state)
+		if err := st.SetDepositRequestsStartIndex(view.Uint64View(opts.DepositRequestsStartIndex)); err != nil {
+			return nil, err
+		}
+		if pendingQueues != nil {
+			if err := SetPendingQueues(spec, st, pendingQueues); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(log, "added %d pending deposits, %d pending partial withdrawals and %d pending consolidations\n",
+				len(pendingQueues.Deposits), len(pendingQueues.PartialWithdrawals), len(pendingQueues.Consolidations))
+		}
+		if churn, err = ReadElectraChurn(st); err != nil {
+			return nil, err
+		}
+	}
+
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		State:                 state,
+		Fork:                  fork,
+		Eth1Block:             eth1Block,
+		ShadowFork:            isShadowFork,
+		Eth1Timestamp:         beaconGenesisTimestamp,
+		GenesisTime:           genesisTime,
+		GenesisValidatorsRoot: vals.HashTreeRoot(tree.GetHashFn()),
+		ValidatorCount:        validatorCount,
+		DepositContract:       depositContract,
+		RejectedDeposits:      rejectedDeposits,
+		Churn:                 churn,
+		Warnings:              warnings,
+	}, nil
+}
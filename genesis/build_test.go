@@ -0,0 +1,285 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+)
+
+func TestBuildPhase0(t *testing.T) {
+	spec := configs.Minimal
+	validators := testValidators(t, spec, 64)
+	res, err := Build(context.Background(), &Options{
+		Spec:               spec,
+		Fork:               "phase0",
+		Eth1BlockHash:      common.Root{1},
+		Eth1BlockTimestamp: 1000,
+		Validators:         validators,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Eth1Block != nil {
+		t.Fatal("expected no execution-layer block")
+	}
+	if res.ValidatorCount != 64 {
+		t.Fatalf("unexpected validator count: %d", res.ValidatorCount)
+	}
+	if res.GenesisTime != spec.MIN_GENESIS_TIME+spec.GENESIS_DELAY {
+		t.Fatalf("unexpected genesis time: %d", res.GenesisTime)
+	}
+	eth1Data, err := res.State.Eth1Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eth1Data.BlockHash != (common.Root{1}) {
+		t.Fatalf("unexpected eth1 block hash: %s", eth1Data.BlockHash)
+	}
+	vals, err := res.State.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := vals.HashTreeRoot(tree.GetHashFn()); root != res.GenesisValidatorsRoot {
+		t.Fatalf("validators root mismatch: %s <> %s", root, res.GenesisValidatorsRoot)
+	}
+}
+
+func TestBuildWarnings(t *testing.T) {
+	spec := configs.Minimal
+	depositDataPath := filepath.Join(t.TempDir(), "deposit_data.json")
+	rejected := testDepositData(t, spec, 11)
+	rejected.Amount = 1_000_000_000
+	writeDepositData(t, depositDataPath, []depositDataEntry{testDepositData(t, spec, 10), rejected})
+	var log bytes.Buffer
+	res, err := Build(context.Background(), &Options{
+		Spec:               spec,
+		Fork:               "phase0",
+		Log:                &log,
+		Eth1BlockTimestamp: 1000,
+		DepositDataPaths:   []string{depositDataPath},
+		Validators:         testValidators(t, spec, 8),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "not enough validators for genesis") {
+		t.Fatalf("unexpected warnings: %q", res.Warnings)
+	}
+	if res.ValidatorCount != 9 {
+		t.Fatalf("unexpected validator count: %d", res.ValidatorCount)
+	}
+	if len(res.RejectedDeposits) != 1 || res.RejectedDeposits[0].Index != 1 {
+		t.Fatalf("unexpected rejected deposits: %v", res.RejectedDeposits)
+	}
+	if res.Churn != nil {
+		t.Fatalf("expected no churn before electra, got %v", res.Churn)
+	}
+	if !strings.Contains(log.String(), "MIN_GENESIS_TIME") {
+		t.Fatalf("expected the genesis time source in the log, got %q", log.String())
+	}
+}
+
+// testValidators creates validators with valid pubkeys, from insecure secret keys 1, 2, 3, etc.
+func testValidators(t testing.TB, spec *common.Spec, count int) []phase0.KickstartValidatorData {
+	validators := make([]phase0.KickstartValidatorData, count)
+	for i := range validators {
+		var skBytes [32]byte
+		binary.BigEndian.PutUint64(skBytes[24:], uint64(i+1))
+		var sk blsu.SecretKey
+		if err := sk.Deserialize(&skBytes); err != nil {
+			t.Fatal(err)
+		}
+		pub, err := blsu.SkToPk(&sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators[i].Pubkey = pub.Serialize()
+		validators[i].Balance = spec.MAX_EFFECTIVE_BALANCE
+	}
+	return validators
+}
+
+func TestBuildElectraCompounding(t *testing.T) {
+	spec := configs.Minimal
+	validators := testValidators(t, spec, 64)
+	validators[0].WithdrawalCredentials[0] = COMPOUNDING_WITHDRAWAL_PREFIX
+	validators[0].Balance = 2048_000_000_000
+	// compounding, but below the activation balance
+	validators[1].WithdrawalCredentials[0] = COMPOUNDING_WITHDRAWAL_PREFIX
+	validators[1].Balance = 16_000_000_000
+	// not compounding, effective balance is capped
+	validators[2].Balance = 2048_000_000_000
+
+	opts := &Options{
+		Spec:               spec,
+		Fork:               "electra",
+		Eth1BlockTimestamp: 1000,
+		Validators:         validators,
+	}
+	res, err := Build(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := res.State.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range []struct {
+		eff    common.Gwei
+		active bool
+	}{
+		{eff: spec.MAX_EFFECTIVE_BALANCE_ELECTRA, active: true},
+		{eff: 16_000_000_000, active: false},
+		{eff: spec.MAX_EFFECTIVE_BALANCE, active: true},
+		{eff: spec.MAX_EFFECTIVE_BALANCE, active: true},
+	} {
+		v, err := vals.Validator(common.ValidatorIndex(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		eff, err := v.EffectiveBalance()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eff != exp.eff {
+			t.Errorf("validator %d: expected effective balance %d, got %d", i, exp.eff, eff)
+		}
+		activation, err := v.ActivationEpoch()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active := activation == common.GENESIS_EPOCH; active != exp.active {
+			t.Errorf("validator %d: expected active %v, got %v", i, exp.active, active)
+		}
+	}
+
+	opts.Fork = "deneb"
+	if _, err := Build(context.Background(), opts); err == nil {
+		t.Fatal("expected compounding validators to be rejected before electra")
+	}
+}
+
+// syncCommitteeIndices returns the validator indices of the current sync committee of the state,
+// after checking that the next sync committee is the same.
+func syncCommitteeIndices(t *testing.T, state common.BeaconState, validators []phase0.KickstartValidatorData) []common.ValidatorIndex {
+	st, ok := state.(common.SyncCommitteeBeaconState)
+	if !ok {
+		t.Fatalf("state %T has no sync committee", state)
+	}
+	committee := func(get func() (*common.SyncCommitteeView, error)) []common.BLSPubkey {
+		c, err := get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubkeys, err := c.Pubkeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		flat, err := pubkeys.Flatten()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return flat
+	}
+	current, next := committee(st.CurrentSyncCommittee), committee(st.NextSyncCommittee)
+	indexOf := make(map[common.BLSPubkey]common.ValidatorIndex, len(validators))
+	for i := range validators {
+		indexOf[validators[i].Pubkey] = common.ValidatorIndex(i)
+	}
+	indices := make([]common.ValidatorIndex, len(current))
+	for i, pub := range current {
+		if next[i] != pub {
+			t.Fatalf("sync committee member %d: next committee pubkey %s differs from current %s", i, next[i], pub)
+		}
+		indices[i] = indexOf[pub]
+	}
+	return indices
+}
+
+func TestGenesisSyncCommittee(t *testing.T) {
+	spec := configs.Minimal
+	validators := testValidators(t, spec, 64)
+	// The expected indices are computed with get_next_sync_committee_indices of the consensus-specs,
+	// for the minimal preset, 64 validators of 32 ETH, and all RANDAO mixes set to the eth1 block hash:
+	// the duplicate committee of initialize_beacon_state_from_eth1, seeded with the epoch after genesis.
+	altairIndices := []common.ValidatorIndex{50, 22, 15, 52, 16, 18, 30, 40, 6, 12, 44, 5, 20, 59, 43, 46,
+		23, 55, 35, 4, 63, 0, 7, 21, 61, 26, 32, 34, 31, 45, 48, 54}
+	// Electra samples with 16-bit random values against MAX_EFFECTIVE_BALANCE_ELECTRA.
+	electraIndices := []common.ValidatorIndex{52, 13, 47, 58, 31, 4, 51, 60, 47, 53, 15, 34, 3, 43, 10, 11,
+		39, 4, 55, 52, 43, 23, 51, 52, 16, 7, 17, 6, 9, 41, 15, 10}
+	for _, tc := range []struct {
+		fork     string
+		expected []common.ValidatorIndex
+	}{
+		{"altair", altairIndices},
+		{"deneb", altairIndices},
+		{"electra", electraIndices},
+		{"fulu", electraIndices},
+	} {
+		t.Run(tc.fork, func(t *testing.T) {
+			res, err := Build(context.Background(), &Options{
+				Spec:               spec,
+				Fork:               tc.fork,
+				Eth1BlockHash:      common.Root{1},
+				Eth1BlockTimestamp: 1000,
+				Validators:         validators,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			indices := syncCommitteeIndices(t, res.State, validators)
+			if !slices.Equal(indices, tc.expected) {
+				t.Fatalf("unexpected sync committee:\n%v <- got\n%v <- expected", indices, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGenesisSyncCommitteeCompounding(t *testing.T) {
+	spec := configs.Minimal
+	validators := testValidators(t, spec, 64)
+	// Even validators are compounding, with 2048 ETH, odd validators have 32 ETH.
+	for i := 0; i < len(validators); i += 2 {
+		validators[i].WithdrawalCredentials[0] = COMPOUNDING_WITHDRAWAL_PREFIX
+		validators[i].Balance = spec.MAX_EFFECTIVE_BALANCE_ELECTRA
+	}
+	res, err := Build(context.Background(), &Options{
+		Spec:               spec,
+		Fork:               "electra",
+		Eth1BlockHash:      common.Root{1},
+		Eth1BlockTimestamp: 1000,
+		Validators:         validators,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indices := syncCommitteeIndices(t, res.State, validators)
+	// Computed with the Electra get_next_sync_committee_indices of the consensus-specs, like TestGenesisSyncCommittee.
+	expected := []common.ValidatorIndex{50, 22, 52, 16, 18, 30, 40, 6, 12, 44, 20, 46, 4, 0, 26, 32,
+		34, 48, 54, 62, 36, 58, 14, 8, 24, 13, 28, 38, 56, 42, 10, 2}
+	if !slices.Equal(indices, expected) {
+		t.Fatalf("unexpected sync committee:\n%v <- got\n%v <- expected", indices, expected)
+	}
+	// A 32 ETH candidate is accepted 1 in 64 times, a 2048 ETH candidate always.
+	// With the phase0 rule, capped at MAX_EFFECTIVE_BALANCE, both would always be accepted.
+	small := 0
+	for _, i := range indices {
+		if i%2 == 1 {
+			small++
+		}
+	}
+	if small > 2 {
+		t.Fatalf("expected the compounding validators to fill the sync committee, got %d of 32 ETH validators", small)
+	}
+}
@@ -0,0 +1,82 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/core"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/codec"
+)
+
+// Bundle is the network config of a testnet, in the layout of the eth-clients testnet repositories.
+type Bundle struct {
+	// ConfigYAML is the consensus-layer config.yaml.
+	ConfigYAML []byte
+	// Eth1Genesis is the execution-layer genesis.json. Not written if nil.
+	Eth1Genesis *core.Genesis
+	Result      *Result
+}
+
+// WriteBundle writes the files of the network config to the directory:
+// config.yaml, genesis.ssz, genesis.json, deploy_block.txt, deposit_contract_block.txt,
+// deposit_contract_block_hash.txt, genesis_validators_root.txt and parsedConsensusGenesis.json.
+//
+// The deposit contract block is the block the deposit contract was deployed in, that clients follow the deposit contract from:
+// the deploy block of the deposit contract state, or block 0 if there is no deposit contract state, like the contract
+// in the genesis of a devnet, with the eth1 block hash of the state. The deposit contract block files are not written
+// if the deposit contract state does not know its deploy block.
+func WriteBundle(spec *common.Spec, dir string, b *Bundle) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output dir: %w", err)
+	}
+	var stateData bytes.Buffer
+	if err := b.Result.State.Serialize(codec.NewEncodingWriter(&stateData)); err != nil {
+		return fmt.Errorf("failed to serialize state: %w", err)
+	}
+	eth1Data, err := b.Result.State.Eth1Data()
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		"config.yaml":                 b.ConfigYAML,
+		"genesis.ssz":                 stateData.Bytes(),
+		"genesis_validators_root.txt": []byte(b.Result.GenesisValidatorsRoot.String() + "\n"),
+	}
+	if dc := b.Result.DepositContract; dc == nil || dc.DeployBlockHash != (common.Root{}) {
+		var deployBlock uint64
+		deployBlockHash := eth1Data.BlockHash
+		if dc != nil {
+			deployBlock, deployBlockHash = dc.DeployBlock, dc.DeployBlockHash
+		}
+		files["deploy_block.txt"] = []byte(fmt.Sprintf("%d\n", deployBlock))
+		files["deposit_contract_block.txt"] = []byte(fmt.Sprintf("%d\n", deployBlock))
+		files["deposit_contract_block_hash.txt"] = []byte(deployBlockHash.String() + "\n")
+	}
+	if b.Eth1Genesis != nil {
+		data, err := json.MarshalIndent(b.Eth1Genesis, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode execution-layer genesis: %w", err)
+		}
+		files["genesis.json"] = data
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "parsedConsensusGenesis.json"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := WriteJSON(f, b.Result.Fork.StateType(spec), stateData.Bytes()); err != nil {
+		return fmt.Errorf("failed to write parsedConsensusGenesis.json: %w", err)
+	}
+	return f.Close()
+}
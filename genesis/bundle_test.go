@@ -0,0 +1,144 @@
+package genesis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestWriteBundle(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ELECTRA_FORK_EPOCH = common.FAR_FUTURE_EPOCH
+	spec.FULU_FORK_EPOCH = common.FAR_FUTURE_EPOCH
+	template := &core.Genesis{
+		Alloc: types.GenesisAlloc{
+			gethcommon.HexToAddress("0x1234"): {Balance: big.NewInt(1e18)},
+		},
+	}
+	eth1Genesis, err := GenerateEth1Genesis(&spec, template, 1000, []byte{0x60, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Build(context.Background(), &Options{
+		Spec:                 &spec,
+		Fork:                 "deneb",
+		Eth1Genesis:          eth1Genesis,
+		MatchEth1GenesisTime: true,
+		Validators:           testValidators(t, &spec, 64),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "network")
+	if err := WriteBundle(&spec, dir, &Bundle{
+		ConfigYAML:  []byte("PRESET_BASE: minimal\n"),
+		Eth1Genesis: eth1Genesis,
+		Result:      res,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	genesisHash := eth1Genesis.ToBlock().Hash().String()
+	for name, expected := range map[string]string{
+		"config.yaml":                     "PRESET_BASE: minimal\n",
+		"deploy_block.txt":                "0\n",
+		"deposit_contract_block.txt":      "0\n",
+		"deposit_contract_block_hash.txt": genesisHash + "\n",
+		"genesis_validators_root.txt":     res.GenesisValidatorsRoot.String() + "\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("unexpected %s: %q", name, data)
+		}
+	}
+
+	// the deploy block of the deposit contract state, if known
+	res.DepositContract = &DepositContractState{DeployBlock: 42, DeployBlockHash: common.Root{0x42}}
+	deployDir := filepath.Join(t.TempDir(), "deploy")
+	if err := WriteBundle(&spec, deployDir, &Bundle{ConfigYAML: []byte("PRESET_BASE: minimal\n"), Result: res}); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]string{
+		"deploy_block.txt":                "42\n",
+		"deposit_contract_block.txt":      "42\n",
+		"deposit_contract_block_hash.txt": common.Root{0x42}.String() + "\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(deployDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("unexpected %s: %q", name, data)
+		}
+	}
+
+	// no deposit contract block files if the deploy block is not known
+	res.DepositContract = &DepositContractState{deployBlockErr: errors.New("missing trie node")}
+	unknownDir := filepath.Join(t.TempDir(), "unknown")
+	if err := WriteBundle(&spec, unknownDir, &Bundle{ConfigYAML: []byte("PRESET_BASE: minimal\n"), Result: res}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"deploy_block.txt", "deposit_contract_block.txt", "deposit_contract_block_hash.txt"} {
+		if _, err := os.Stat(filepath.Join(unknownDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected no %s, got %v", name, err)
+		}
+	}
+
+	stateData, err := os.ReadFile(filepath.Join(dir, "genesis.ssz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, fork, err := DecodeState(&spec, stateData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root, err := state.GenesisValidatorsRoot(); err != nil || fork.Name != "deneb" || root != res.GenesisValidatorsRoot {
+		t.Fatalf("unexpected %s genesis state", fork.Name)
+	}
+
+	el, err := LoadEth1GenesisConf(filepath.Join(dir, "genesis.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if el.ToBlock().Hash().String() != genesisHash {
+		t.Fatal("unexpected execution-layer genesis")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "parsedConsensusGenesis.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		GenesisTime                  string `json:"genesis_time"`
+		GenesisValidatorsRoot        string `json:"genesis_validators_root"`
+		Validators                   []any  `json:"validators"`
+		LatestExecutionPayloadHeader struct {
+			BlockHash string `json:"block_hash"`
+		} `json:"latest_execution_payload_header"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.GenesisTime != "1300" || parsed.GenesisValidatorsRoot != res.GenesisValidatorsRoot.String() ||
+		len(parsed.Validators) != 64 || parsed.LatestExecutionPayloadHeader.BlockHash != genesisHash {
+		t.Fatalf("unexpected parsed genesis %+v", parsed)
+	}
+}
@@ -0,0 +1,126 @@
+package genesis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// GenesisFork returns the latest fork that the consensus-layer config schedules at the genesis epoch.
+func GenesisFork(spec *common.Spec) *Fork {
+	fork := Forks[0]
+	for _, f := range Forks[1:] {
+		if f.Epoch(spec) == common.GENESIS_EPOCH {
+			fork = f
+		}
+	}
+	return fork
+}
+
+// CheckConfigs compares the execution-layer genesis config with the consensus-layer config, and returns every mismatch.
+//
+// The fork is the fork of the genesis state: it and all forks before it must be scheduled at the genesis epoch
+// in the consensus-layer config, and their execution-layer upgrades must be active in eth1Block,
+// the execution-layer genesis block (or shadow-fork block).
+// The execution-layer upgrades of later forks must be scheduled at the start of the fork epoch,
+// counted from the beacon genesis time.
+func CheckConfigs(spec *common.Spec, fork *Fork, eth1Genesis *core.Genesis, eth1Block *types.Block, genesisTime common.Timestamp) []error {
+	cfg := eth1Genesis.Config
+	if cfg == nil {
+		return []error{fmt.Errorf("execution-layer genesis has no chain config")}
+	}
+	var errs []error
+	mismatch := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if cfg.ChainID == nil || !cfg.ChainID.IsUint64() || cfg.ChainID.Uint64() != uint64(spec.DEPOSIT_CHAIN_ID) {
+		mismatch("execution-layer chain ID %v does not match DEPOSIT_CHAIN_ID %d", cfg.ChainID, spec.DEPOSIT_CHAIN_ID)
+	}
+	// Older execution-layer configs do not specify the deposit contract
+	if (cfg.DepositContractAddress != [20]byte{}) && cfg.DepositContractAddress != [20]byte(spec.DEPOSIT_CONTRACT_ADDRESS) {
+		mismatch("execution-layer deposit contract address %s does not match DEPOSIT_CONTRACT_ADDRESS %s",
+			cfg.DepositContractAddress, spec.DEPOSIT_CONTRACT_ADDRESS)
+	}
+	if eth1Block.Time() > uint64(genesisTime) {
+		mismatch("execution-layer block timestamp %d is after the beacon genesis time %d (GENESIS_DELAY is %d)",
+			eth1Block.Time(), genesisTime, spec.GENESIS_DELAY)
+	}
+
+	if fork.AtLeast("bellatrix") {
+		if cfg.TerminalTotalDifficulty == nil {
+			mismatch("execution-layer terminal total difficulty is not set, but the %s genesis state is post-merge", fork.Name)
+		} else if eth1Block.NumberU64() == 0 && cfg.TerminalTotalDifficulty.Sign() != 0 {
+			mismatch("execution-layer terminal total difficulty is %s, but the %s genesis state is post-merge and needs 0",
+				cfg.TerminalTotalDifficulty, fork.Name)
+		}
+	} else if spec.BELLATRIX_FORK_EPOCH != common.FAR_FUTURE_EPOCH {
+		ttd := uint256.Int(spec.TERMINAL_TOTAL_DIFFICULTY)
+		if cfg.TerminalTotalDifficulty == nil || cfg.TerminalTotalDifficulty.Cmp(ttd.ToBig()) != 0 {
+			mismatch("execution-layer terminal total difficulty %v does not match TERMINAL_TOTAL_DIFFICULTY %s",
+				cfg.TerminalTotalDifficulty, ttd.Dec())
+		}
+	}
+
+	slotsPerEpoch := uint64(spec.SLOTS_PER_EPOCH)
+	secondsPerSlot := uint64(spec.SECONDS_PER_SLOT)
+	atGenesis := true
+	for _, f := range Forks {
+		scheduled := atGenesis || f.Epoch(spec) != common.FAR_FUTURE_EPOCH
+		if atGenesis && f.Epoch(spec) != common.GENESIS_EPOCH {
+			mismatch("%s is active in the genesis state, but %s_FORK_EPOCH is %d", f.Name, strings.ToUpper(f.Name), f.Epoch(spec))
+		}
+		if f.Eth1ForkTime != nil {
+			elTime := f.Eth1ForkTime(cfg)
+			if atGenesis {
+				if elTime == nil || *elTime > eth1Block.Time() {
+					mismatch("%s is active in the genesis state, but execution-layer %s is not active at block timestamp %d (%s)",
+						f.Name, f.Eth1ForkName, eth1Block.Time(), fmtForkTime(elTime))
+				}
+			} else if !scheduled {
+				if elTime != nil {
+					mismatch("%s is not scheduled, but execution-layer %s is (%s)", f.Name, f.Eth1ForkName, fmtForkTime(elTime))
+				}
+			} else {
+				epoch := uint64(f.Epoch(spec))
+				expected := uint64(genesisTime) + epoch*slotsPerEpoch*secondsPerSlot
+				if elTime == nil || *elTime != expected {
+					mismatch("%s is scheduled at epoch %d, timestamp %d, but execution-layer %s is %s",
+						f.Name, epoch, expected, f.Eth1ForkName, fmtForkTime(elTime))
+				}
+			}
+		}
+		if scheduled && f.MaxBlobsPerBlock != nil {
+			if blobConfig := f.Eth1BlobConfig(cfg); blobConfig != nil && uint64(blobConfig.Max) != f.MaxBlobsPerBlock(spec) {
+				mismatch("%s allows %d blobs per block, but the execution-layer %s blob config allows %d",
+					f.Name, f.MaxBlobsPerBlock(spec), f.Eth1ForkName, blobConfig.Max)
+			}
+		}
+		if f == fork {
+			atGenesis = false
+		}
+	}
+	return errs
+}
+
+// UncheckedConfigs returns the parts of the configs of the scheduled forks that CheckConfigs cannot compare.
+func UncheckedConfigs(spec *common.Spec) []string {
+	var unchecked []string
+	if spec.FULU_FORK_EPOCH != common.FAR_FUTURE_EPOCH {
+		// The execution-layer chain config has no Osaka blob config to compare the BLOB_SCHEDULE with.
+		unchecked = append(unchecked, "the fulu BLOB_SCHEDULE is not checked against the execution-layer blob config")
+	}
+	return unchecked
+}
+
+func fmtForkTime(t *uint64) string {
+	if t == nil {
+		return "not scheduled"
+	}
+	return fmt.Sprintf("at timestamp %d", *t)
+}
@@ -0,0 +1,166 @@
+package genesis
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestCheckConfigs(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ELECTRA_FORK_EPOCH = 2
+	spec.FULU_FORK_EPOCH = common.FAR_FUTURE_EPOCH
+
+	const eth1Time = 1000
+	genesisTime := common.Timestamp(eth1Time) + spec.GENESIS_DELAY
+	pragueTime := uint64(genesisTime) + 2*uint64(spec.SLOTS_PER_EPOCH)*uint64(spec.SECONDS_PER_SLOT)
+
+	newEth1Genesis := func() *core.Genesis {
+		return &core.Genesis{
+			Config: &params.ChainConfig{
+				ChainID:                 big.NewInt(int64(spec.DEPOSIT_CHAIN_ID)),
+				HomesteadBlock:          big.NewInt(0),
+				EIP150Block:             big.NewInt(0),
+				EIP155Block:             big.NewInt(0),
+				EIP158Block:             big.NewInt(0),
+				ByzantiumBlock:          big.NewInt(0),
+				ConstantinopleBlock:     big.NewInt(0),
+				PetersburgBlock:         big.NewInt(0),
+				IstanbulBlock:           big.NewInt(0),
+				BerlinBlock:             big.NewInt(0),
+				LondonBlock:             big.NewInt(0),
+				ShanghaiTime:            new(uint64),
+				CancunTime:              new(uint64),
+				PragueTime:              &pragueTime,
+				TerminalTotalDifficulty: big.NewInt(0),
+				DepositContractAddress:  [20]byte(spec.DEPOSIT_CONTRACT_ADDRESS),
+				BlobScheduleConfig: &params.BlobScheduleConfig{
+					Cancun: params.DefaultCancunBlobConfig,
+					Prague: params.DefaultPragueBlobConfig,
+				},
+			},
+			Timestamp:     eth1Time,
+			GasLimit:      36_000_000,
+			Difficulty:    big.NewInt(0),
+			BaseFee:       big.NewInt(7),
+			ExcessBlobGas: new(uint64),
+			BlobGasUsed:   new(uint64),
+		}
+	}
+	deneb, err := ForkByName("deneb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork := GenesisFork(&spec); fork != deneb {
+		t.Fatalf("expected deneb genesis fork, got %s", fork.Name)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		modify   func(g *core.Genesis)
+		mismatch []string
+	}{
+		{name: "match", modify: func(g *core.Genesis) {}},
+		{
+			name: "prague off by one",
+			modify: func(g *core.Genesis) {
+				t := pragueTime + 1
+				g.Config.PragueTime = &t
+			},
+			mismatch: []string{"electra is scheduled at epoch 2"},
+		},
+		{
+			name: "unexpected osaka, no cancun",
+			modify: func(g *core.Genesis) {
+				g.Config.OsakaTime = &pragueTime
+				g.Config.CancunTime = nil
+			},
+			mismatch: []string{"deneb is active in the genesis state", "fulu is not scheduled"},
+		},
+		{
+			name: "chain and deposit contract",
+			modify: func(g *core.Genesis) {
+				g.Config.ChainID = big.NewInt(123)
+				g.Config.DepositContractAddress[0] ^= 1
+			},
+			mismatch: []string{"chain ID 123", "deposit contract address"},
+		},
+		{
+			name:     "genesis delay",
+			modify:   func(g *core.Genesis) { g.Timestamp = uint64(genesisTime) + 1 },
+			mismatch: []string{"after the beacon genesis time"},
+		},
+		{
+			name: "blobs and ttd",
+			modify: func(g *core.Genesis) {
+				g.Config.BlobScheduleConfig.Prague = &params.BlobConfig{Target: 6, Max: 12, UpdateFraction: 5007716}
+				g.Config.TerminalTotalDifficulty = big.NewInt(1)
+			},
+			mismatch: []string{"terminal total difficulty", "electra allows 9 blobs per block"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newEth1Genesis()
+			tc.modify(g)
+			errs := CheckConfigs(&spec, deneb, g, g.ToBlock(), genesisTime)
+			if len(errs) != len(tc.mismatch) {
+				t.Fatalf("expected %d mismatches, got %v", len(tc.mismatch), errs)
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), tc.mismatch[i]) {
+					t.Errorf("expected mismatch %q, got %q", tc.mismatch[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckConfigsGenesisForkEpochs(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ELECTRA_FORK_EPOCH = 0
+	spec.FULU_FORK_EPOCH = common.FAR_FUTURE_EPOCH
+	electra, err := ForkByName("electra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eth1Genesis, err := GenerateEth1Genesis(&spec, &core.Genesis{}, 1000, []byte{0x60, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesisTime := common.Timestamp(eth1Genesis.Timestamp) + spec.GENESIS_DELAY
+	if errs := CheckConfigs(&spec, electra, eth1Genesis, eth1Genesis.ToBlock(), genesisTime); len(errs) != 0 {
+		t.Fatalf("unexpected mismatches: %v", errs)
+	}
+	// an electra genesis state, with deneb scheduled later in the consensus-layer config
+	spec.DENEB_FORK_EPOCH = 10
+	errs := CheckConfigs(&spec, electra, eth1Genesis, eth1Genesis.ToBlock(), genesisTime)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "deneb is active in the genesis state, but DENEB_FORK_EPOCH is 10") {
+		t.Fatalf("expected deneb fork epoch mismatch, got %v", errs)
+	}
+}
+
+func TestUncheckedConfigs(t *testing.T) {
+	spec := *configs.Minimal
+	spec.FULU_FORK_EPOCH = common.FAR_FUTURE_EPOCH
+	if unchecked := UncheckedConfigs(&spec); len(unchecked) != 0 {
+		t.Fatalf("unexpected unchecked configs without fulu: %q", unchecked)
+	}
+	spec.FULU_FORK_EPOCH = 10
+	if unchecked := UncheckedConfigs(&spec); len(unchecked) != 1 || !strings.Contains(unchecked[0], "BLOB_SCHEDULE") {
+		t.Fatalf("unexpected unchecked configs with fulu: %q", unchecked)
+	}
+}
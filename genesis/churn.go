@@ -0,0 +1,82 @@
+package genesis
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+)
+
+// ElectraChurn is the exit and consolidation churn of an Electra or later state.
+type ElectraChurn struct {
+	EarliestExitEpoch             common.Epoch
+	EarliestConsolidationEpoch    common.Epoch
+	ExitBalanceToConsume          common.Gwei
+	ConsolidationBalanceToConsume common.Gwei
+}
+
+// ReadElectraChurn reads the exit and consolidation churn of the state.
+func ReadElectraChurn(state *electra.BeaconStateView) (*ElectraChurn, error) {
+	var churn ElectraChurn
+	var err error
+	if churn.EarliestExitEpoch, err = state.EarliestExitEpoch(); err != nil {
+		return nil, err
+	}
+	if churn.EarliestConsolidationEpoch, err = state.EarliestConsolidationEpoch(); err != nil {
+		return nil, err
+	}
+	if churn.ExitBalanceToConsume, err = state.ExitBalanceToConsume(); err != nil {
+		return nil, err
+	}
+	if churn.ConsolidationBalanceToConsume, err = state.ConsolidationBalanceToConsume(); err != nil {
+		return nil, err
+	}
+	return &churn, nil
+}
+
+// TotalActiveBalance is the sum of the effective balances of the validators active at the given epoch,
+// like get_total_active_balance: at least one EFFECTIVE_BALANCE_INCREMENT, to avoid divisions by zero.
+func TotalActiveBalance(spec *common.Spec, vals common.ValidatorRegistry, epoch common.Epoch) (common.Gwei, error) {
+	indicesBounded, err := common.LoadBoundedIndices(vals)
+	if err != nil {
+		return 0, err
+	}
+	var total common.Gwei
+	for _, index := range common.ActiveIndices(indicesBounded, epoch) {
+		v, err := vals.Validator(index)
+		if err != nil {
+			return 0, err
+		}
+		eff, err := v.EffectiveBalance()
+		if err != nil {
+			return 0, err
+		}
+		total += eff
+	}
+	if total < spec.EFFECTIVE_BALANCE_INCREMENT {
+		total = spec.EFFECTIVE_BALANCE_INCREMENT
+	}
+	return total, nil
+}
+
+// BalanceChurnLimit is the Electra get_balance_churn_limit, for the given total active balance.
+func BalanceChurnLimit(spec *common.Spec, totalActiveBalance common.Gwei) common.Gwei {
+	churn := totalActiveBalance / common.Gwei(spec.CHURN_LIMIT_QUOTIENT)
+	if minChurn := common.Gwei(spec.MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA); churn < minChurn {
+		churn = minChurn
+	}
+	return churn - churn%spec.EFFECTIVE_BALANCE_INCREMENT
+}
+
+// ActivationExitChurnLimit is the Electra get_activation_exit_churn_limit, for the given total active balance.
+func ActivationExitChurnLimit(spec *common.Spec, totalActiveBalance common.Gwei) common.Gwei {
+	churn := BalanceChurnLimit(spec, totalActiveBalance)
+	if maxChurn := common.Gwei(spec.MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT); churn > maxChurn {
+		churn = maxChurn
+	}
+	return churn
+}
+
+// ConsolidationChurnLimit is the Electra get_consolidation_churn_limit, for the given total active balance.
+// This is zero until the balance churn exceeds MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT.
+func ConsolidationChurnLimit(spec *common.Spec, totalActiveBalance common.Gwei) common.Gwei {
+	return BalanceChurnLimit(spec, totalActiveBalance) - ActivationExitChurnLimit(spec, totalActiveBalance)
+}
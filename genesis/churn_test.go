@@ -0,0 +1,82 @@
+package genesis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestChurnLimits(t *testing.T) {
+	spec := configs.Mainnet
+	const eth = common.Gwei(1_000_000_000)
+	// The balance churn exceeds MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT (256 ETH)
+	// at a total active balance of 257 * CHURN_LIMIT_QUOTIENT ETH.
+	threshold := 257 * eth * common.Gwei(spec.CHURN_LIMIT_QUOTIENT)
+	for _, tc := range []struct {
+		name          string
+		total         common.Gwei
+		exit          common.Gwei
+		consolidation common.Gwei
+	}{
+		{"empty", spec.EFFECTIVE_BALANCE_INCREMENT, 128 * eth, 0},
+		{"min churn", 128 * eth * common.Gwei(spec.CHURN_LIMIT_QUOTIENT), 128 * eth, 0},
+		{"exit churn", 200 * eth * common.Gwei(spec.CHURN_LIMIT_QUOTIENT), 200 * eth, 0},
+		{"below threshold", threshold - 1, 256 * eth, 0},
+		{"at threshold", threshold, 256 * eth, 1 * eth},
+		{"above threshold", 1000 * eth * common.Gwei(spec.CHURN_LIMIT_QUOTIENT), 256 * eth, 744 * eth},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ActivationExitChurnLimit(spec, tc.total); got != tc.exit {
+				t.Errorf("expected activation exit churn %d, got %d", tc.exit, got)
+			}
+			if got := ConsolidationChurnLimit(spec, tc.total); got != tc.consolidation {
+				t.Errorf("expected consolidation churn %d, got %d", tc.consolidation, got)
+			}
+		})
+	}
+}
+
+func TestBuildElectraChurn(t *testing.T) {
+	spec := configs.Minimal
+	// With the minimal config the consolidation churn starts at
+	// (MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT + 1 ETH) * CHURN_LIMIT_QUOTIENT = 129 * 32 * 32 ETH.
+	for _, tc := range []struct {
+		validators    int
+		exit          common.Gwei
+		consolidation common.Gwei
+	}{
+		{validators: 128, exit: 128_000_000_000, consolidation: 0},
+		{validators: 129, exit: 128_000_000_000, consolidation: 1_000_000_000},
+	} {
+		res, err := Build(context.Background(), &Options{
+			Spec:               spec,
+			Fork:               "electra",
+			Eth1BlockTimestamp: 1000,
+			Validators:         testValidators(t, spec, tc.validators),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := res.State.(*electra.BeaconStateView)
+		exit, err := state.ExitBalanceToConsume()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exit != tc.exit {
+			t.Errorf("%d validators: expected exit balance to consume %d, got %d", tc.validators, tc.exit, exit)
+		}
+		consolidation, err := state.ConsolidationBalanceToConsume()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if consolidation != tc.consolidation {
+			t.Errorf("%d validators: expected consolidation balance to consume %d, got %d", tc.validators, tc.consolidation, consolidation)
+		}
+		if res.Churn == nil || res.Churn.ExitBalanceToConsume != exit || res.Churn.ConsolidationBalanceToConsume != consolidation {
+			t.Errorf("%d validators: unexpected churn result %v", tc.validators, res.Churn)
+		}
+	}
+}
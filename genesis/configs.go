@@ -1,4 +1,4 @@
-package main
+package genesis
 
 import (
 	"bytes"
@@ -8,7 +8,7 @@ import (
 	"io/ioutil"
 )
 
-func loadEth1GenesisConf(configPath string) (*core.Genesis, error) {
+func LoadEth1GenesisConf(configPath string) (*core.Genesis, error) {
 	eth1ConfData, err := ioutil.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read eth1 config file: %v", err)
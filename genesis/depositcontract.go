@@ -0,0 +1,357 @@
+package genesis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum"
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// Function selectors of the deposit contract getters.
+var (
+	getDepositRootSelector  = []byte{0xc5, 0xf2, 0x89, 0x2f} // get_deposit_root()
+	getDepositCountSelector = []byte{0x62, 0x1f, 0xd1, 0x30} // get_deposit_count()
+)
+
+// depositEventTopic is the topic of the DepositEvent logs of the deposit contract.
+var depositEventTopic = crypto.Keccak256Hash([]byte("DepositEvent(bytes,bytes,bytes,bytes,bytes)"))
+
+// eth1DepositIndexField is the field index of eth1_deposit_index, the same in the states of all forks.
+const eth1DepositIndexField = 10
+
+// The deposit contract keeps the branch of its incremental merkle tree in storage slots 0 to 31, and the deposit count in slot 32.
+const depositContractCountSlot = depositContractTreeDepth
+
+// DepositContractState is the deposit tree of the deposit contract, as of the execution-layer block of the genesis state.
+type DepositContractState struct {
+	DepositRoot  common.Root
+	DepositCount uint64
+	// Finalized are the roots of the complete subtrees of the deposit tree, from left to right, like in an EIP-4881 snapshot.
+	// Together with the count, they are all of the tree that is needed to add deposits.
+	Finalized []common.Root
+	// DeployBlock is the execution-layer block the deposit contract was deployed in, with hash DeployBlockHash:
+	// the block clients follow the deposit contract from. Both are zero if unknown.
+	DeployBlock     uint64
+	DeployBlockHash common.Root
+
+	// deployBlockErr is why the deploy block is not known, nil if it is.
+	deployBlockErr error
+}
+
+// Eth1Data returns the eth1 data of the deposit contract state, at the execution-layer block with the given hash.
+func (s *DepositContractState) Eth1Data(blockHash common.Root) common.Eth1Data {
+	return common.Eth1Data{
+		DepositRoot:  s.DepositRoot,
+		DepositCount: common.DepositIndex(s.DepositCount),
+		BlockHash:    blockHash,
+	}
+}
+
+// SetDepositContractState sets the eth1 data of the state to the deposit contract state, and the eth1_deposit_index
+// to the deposit count, as if all deposits so far were processed. The deposits after genesis then continue
+// from the deposit count, like on the network that the deposit contract is of.
+func SetDepositContractState(state common.BeaconState, dc *DepositContractState, eth1BlockHash common.Root) error {
+	if err := state.SetEth1Data(dc.Eth1Data(eth1BlockHash)); err != nil {
+		return err
+	}
+	st, ok := state.(interface {
+		Set(i uint64, v view.View) error
+	})
+	if !ok {
+		return fmt.Errorf("cannot set the deposit index of a %T", state)
+	}
+	return st.Set(eth1DepositIndexField, view.Uint64View(dc.DepositCount))
+}
+
+// FetchDepositContractState calls get_deposit_root and get_deposit_count of the deposit contract,
+// at the execution-layer block with the given hash. The finalized roots are read from the storage of the contract,
+// and checked against the deposit root.
+func FetchDepositContractState(ctx context.Context, eth1RPC string, contract common.Eth1Address, blockHash common.Root) (*DepositContractState, error) {
+	client, err := ethclient.Dial(eth1RPC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial deposit contract RPC: %w", err)
+	}
+	defer client.Close()
+	to := gethcommon.Address(contract)
+	block := rpc.BlockNumberOrHashWithHash(gethcommon.Hash(blockHash), false)
+	call := func(selector []byte) ([]byte, error) {
+		return client.CallContractAtHash(ctx, ethereum.CallMsg{To: &to, Data: selector}, gethcommon.Hash(blockHash))
+	}
+
+	rootData, err := call(getDepositRootSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call get_deposit_root of deposit contract %s at block %s: %w", contract, blockHash, err)
+	}
+	if len(rootData) != 32 {
+		return nil, fmt.Errorf("unexpected get_deposit_root result of %d bytes, is there a deposit contract at %s?", len(rootData), contract)
+	}
+	countData, err := call(getDepositCountSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call get_deposit_count of deposit contract %s at block %s: %w", contract, blockHash, err)
+	}
+	count, err := abiBytes(countData, 0, 8)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected get_deposit_count result: %w", err)
+	}
+	dc := &DepositContractState{
+		DepositRoot:  common.Root(rootData),
+		DepositCount: binary.LittleEndian.Uint64(count),
+	}
+
+	// The branch and the count, in a single batch, so they are of the same block.
+	slots := make([]hexutil.Bytes, depositContractCountSlot+1)
+	batch := make([]rpc.BatchElem, len(slots))
+	for i := range batch {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getStorageAt",
+			Args:   []any{to, gethcommon.BigToHash(big.NewInt(int64(i))), block},
+			Result: &slots[i],
+		}
+	}
+	if err := client.Client().BatchCallContext(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to read storage of deposit contract %s at block %s: %w", contract, blockHash, err)
+	}
+	var t depositTree
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("failed to read storage slot %d of deposit contract %s at block %s: %w", i, contract, blockHash, elem.Error)
+		}
+		if len(slots[i]) != 32 {
+			return nil, fmt.Errorf("unexpected storage slot %d of %d bytes", i, len(slots[i]))
+		}
+		if i < depositContractCountSlot {
+			t.branch[i] = common.Root(slots[i])
+		}
+	}
+	storedCount, ok := abiUint64(slots[depositContractCountSlot], 0)
+	if !ok || storedCount != dc.DepositCount {
+		return nil, fmt.Errorf("deposit count in storage %x does not match get_deposit_count %d", slots[depositContractCountSlot], dc.DepositCount)
+	}
+	t.count = storedCount
+	if root := t.root(); root != dc.DepositRoot {
+		return nil, fmt.Errorf("deposit root of the branch in storage %s does not match get_deposit_root %s", root, dc.DepositRoot)
+	}
+	dc.Finalized = t.finalized()
+
+	// The deploy block is the first block with the contract code. Unknown if the node does not have the state of older blocks.
+	head, err := client.HeaderByHash(ctx, gethcommon.Hash(blockHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution-layer block %s: %w", blockHash, err)
+	}
+	if deployBlock, err := findDeployBlock(ctx, client, to, head.Number.Uint64()); err != nil {
+		dc.deployBlockErr = fmt.Errorf("failed to find it, the RPC may not have the state of older blocks: %w", err)
+	} else {
+		deployHeader, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(deployBlock))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deposit contract deploy block %d: %w", deployBlock, err)
+		}
+		dc.DeployBlock = deployBlock
+		dc.DeployBlockHash = common.Root(deployHeader.Hash())
+	}
+	return dc, nil
+}
+
+// findDeployBlock finds the first block up to the head block number with the code of the contract, with a binary search.
+func findDeployBlock(ctx context.Context, client *ethclient.Client, contract gethcommon.Address, head uint64) (uint64, error) {
+	lo, hi := uint64(0), head
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		code, err := client.CodeAt(ctx, contract, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return 0, err
+		}
+		if len(code) > 0 {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, nil
+}
+
+// LoadDepositContractLogs rebuilds the deposit tree of the deposit contract from a dump of its DepositEvent logs,
+// like the result of eth_getLogs: a JSON list of logs, or a JSON-RPC response with the list as result.
+// The logs of other contracts and events, and removed logs, are ignored. The logs must be complete and in order,
+// up to the execution-layer block of the genesis state, with the given block number.
+// The logs do not have the deployment of the contract: the deploy block is the block of the first deposit, if any.
+func LoadDepositContractLogs(path string, contract common.Eth1Address, blockNumber uint64) (*DepositContractState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deposit contract logs: %w", err)
+	}
+	var logs []types.Log
+	var resultData JSONData
+	if err := json.Unmarshal(data, &resultData); err == nil && resultData.Result != nil {
+		data = resultData.Result
+	}
+	if err := json.Unmarshal(data, &logs); err != nil {
+		return nil, fmt.Errorf("failed to decode deposit contract logs %s: %w", path, err)
+	}
+	var t depositTree
+	var deployBlock uint64
+	var deployBlockHash common.Root
+	hFn := tree.GetHashFn()
+	for i := range logs {
+		l := &logs[i]
+		if l.Removed || l.Address != gethcommon.Address(contract) || len(l.Topics) == 0 || l.Topics[0] != depositEventTopic {
+			continue
+		}
+		if l.BlockNumber > blockNumber {
+			return nil, fmt.Errorf("deposit log %d is of block %d, after the execution-layer block %d", i, l.BlockNumber, blockNumber)
+		}
+		dep, index, err := decodeDepositEvent(l.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deposit log %d (tx %s): %w", i, l.TxHash, err)
+		}
+		if index != t.count {
+			return nil, fmt.Errorf("deposit log %d has deposit index %d, expected %d: the logs are incomplete or out of order", i, index, t.count)
+		}
+		if t.count == 0 {
+			deployBlock, deployBlockHash = l.BlockNumber, common.Root(l.BlockHash)
+		}
+		if err := t.push(dep.HashTreeRoot(hFn)); err != nil {
+			return nil, err
+		}
+	}
+	dc := &DepositContractState{
+		DepositRoot:     t.root(),
+		DepositCount:    t.count,
+		Finalized:       t.finalized(),
+		DeployBlock:     deployBlock,
+		DeployBlockHash: deployBlockHash,
+	}
+	if t.count == 0 {
+		dc.deployBlockErr = errors.New("there are no deposit logs")
+	}
+	return dc, nil
+}
+
+// decodeDepositEvent decodes the ABI-encoded data of a DepositEvent log:
+// the pubkey, withdrawal credentials, amount, signature and index, all as bytes.
+func decodeDepositEvent(data []byte) (*common.DepositData, uint64, error) {
+	var dep common.DepositData
+	pubkey, err := abiBytes(data, 0, len(dep.Pubkey))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid pubkey: %w", err)
+	}
+	creds, err := abiBytes(data, 1, len(dep.WithdrawalCredentials))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid withdrawal credentials: %w", err)
+	}
+	amount, err := abiBytes(data, 2, 8)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid amount: %w", err)
+	}
+	signature, err := abiBytes(data, 3, len(dep.Signature))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid signature: %w", err)
+	}
+	index, err := abiBytes(data, 4, 8)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid index: %w", err)
+	}
+	copy(dep.Pubkey[:], pubkey)
+	copy(dep.WithdrawalCredentials[:], creds)
+	dep.Amount = common.Gwei(binary.LittleEndian.Uint64(amount))
+	copy(dep.Signature[:], signature)
+	return &dep, binary.LittleEndian.Uint64(index), nil
+}
+
+// abiBytes returns the ABI-encoded bytes value of the given size, of which the offset is in the given word of the data.
+func abiBytes(data []byte, word int, size int) ([]byte, error) {
+	offset, ok := abiUint64(data, uint64(word)*32)
+	if !ok {
+		return nil, fmt.Errorf("invalid offset")
+	}
+	length, ok := abiUint64(data, offset)
+	if !ok || length != uint64(size) {
+		return nil, fmt.Errorf("expected %d bytes", size)
+	}
+	if offset+32+length > uint64(len(data)) {
+		return nil, fmt.Errorf("data of %d bytes is too short", len(data))
+	}
+	return data[offset+32 : offset+32+length], nil
+}
+
+// abiUint64 decodes the ABI-encoded uint256 word at the given position, if it fits in 64 bits.
+func abiUint64(data []byte, pos uint64) (uint64, bool) {
+	if pos > uint64(len(data)) || uint64(len(data))-pos < 32 {
+		return 0, false
+	}
+	word := data[pos : pos+32]
+	for _, b := range word[:24] {
+		if b != 0 {
+			return 0, false
+		}
+	}
+	return binary.BigEndian.Uint64(word[24:]), true
+}
+
+// depositTree is the incremental merkle tree of the deposit contract: the branch of the last deposit, and the deposit count.
+type depositTree struct {
+	branch [depositContractTreeDepth]common.Root
+	count  uint64
+}
+
+// push adds a deposit leaf, like the deposit function of the deposit contract.
+func (t *depositTree) push(leaf common.Root) error {
+	if t.count >= 1<<depositContractTreeDepth-1 {
+		return fmt.Errorf("deposit tree is full")
+	}
+	t.count++
+	node := leaf
+	size := t.count
+	for height := 0; height < depositContractTreeDepth; height++ {
+		if size&1 == 1 {
+			t.branch[height] = node
+			return nil
+		}
+		node = sha256.Sum256(append(t.branch[height][:], node[:]...))
+		size /= 2
+	}
+	panic("unreachable")
+}
+
+// root is the deposit root, like get_deposit_root of the deposit contract: the tree root, mixed in with the count.
+func (t *depositTree) root() common.Root {
+	var node common.Root
+	size := t.count
+	for height := 0; height < depositContractTreeDepth; height++ {
+		if size&1 == 1 {
+			node = sha256.Sum256(append(t.branch[height][:], node[:]...))
+		} else {
+			node = sha256.Sum256(append(node[:], tree.ZeroHashes[height][:]...))
+		}
+		size /= 2
+	}
+	var count common.Root
+	binary.LittleEndian.PutUint64(count[:], t.count)
+	return sha256.Sum256(append(node[:], count[:]...))
+}
+
+// finalized returns the roots of the complete subtrees, from left to right: the branch at the heights of the bits of the count.
+func (t *depositTree) finalized() []common.Root {
+	finalized := []common.Root{}
+	for height := depositContractTreeDepth - 1; height >= 0; height-- {
+		if (t.count>>height)&1 == 1 {
+			finalized = append(finalized, t.branch[height])
+		}
+	}
+	return finalized
+}
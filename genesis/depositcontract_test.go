@@ -0,0 +1,333 @@
+package genesis
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// abiEncodeBytes ABI-encodes the values as a tuple of bytes values.
+func abiEncodeBytes(values ...[]byte) []byte {
+	head := make([]byte, 32*len(values))
+	var tail []byte
+	for i, v := range values {
+		binary.BigEndian.PutUint64(head[i*32+24:], uint64(len(head)+len(tail)))
+		length := make([]byte, 32)
+		binary.BigEndian.PutUint64(length[24:], uint64(len(v)))
+		tail = append(tail, length...)
+		tail = append(tail, v...)
+		tail = append(tail, make([]byte, (32-len(v)%32)%32)...)
+	}
+	return append(head, tail...)
+}
+
+func depositEventLog(contract common.Eth1Address, blockNumber uint64, dep *common.DepositData, index uint64) types.Log {
+	amount := binary.LittleEndian.AppendUint64(nil, uint64(dep.Amount))
+	indexData := binary.LittleEndian.AppendUint64(nil, index)
+	return types.Log{
+		Address:     gethcommon.Address(contract),
+		Topics:      []gethcommon.Hash{depositEventTopic},
+		Data:        abiEncodeBytes(dep.Pubkey[:], dep.WithdrawalCredentials[:], amount, dep.Signature[:], indexData),
+		BlockNumber: blockNumber,
+		BlockHash:   gethcommon.Hash{byte(blockNumber)},
+	}
+}
+
+func TestLoadDepositContractLogs(t *testing.T) {
+	spec := configs.Minimal
+	validators := testValidators(t, spec, 70)
+	contract := spec.DEPOSIT_CONTRACT_ADDRESS
+	expected := phase0.NewDepositRootsView()
+	var logs []types.Log
+	for i, v := range validators[64:] {
+		dep := &common.DepositData{
+			Pubkey:                v.Pubkey,
+			WithdrawalCredentials: v.WithdrawalCredentials,
+			Amount:                v.Balance,
+			Signature:             common.BLSSignature{byte(i)},
+		}
+		logs = append(logs, depositEventLog(contract, uint64(100+i), dep, uint64(i)))
+		root := view.RootView(dep.HashTreeRoot(tree.GetHashFn()))
+		if err := expected.Append(&root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Logs of other contracts, and removed logs, are ignored.
+	other := logs[0]
+	other.Address = gethcommon.Address{1}
+	removed := logs[1]
+	removed.Removed = true
+	logs = append(logs, other, removed)
+
+	dir := t.TempDir()
+	logsPath := filepath.Join(dir, "logs.json")
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "result": logs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logsPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dc, err := LoadDepositContractLogs(logsPath, contract, 105)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dc.DepositCount != 6 {
+		t.Fatalf("unexpected deposit count %d", dc.DepositCount)
+	}
+	// the block of the first deposit
+	if dc.DeployBlock != 100 || dc.DeployBlockHash != (common.Root{100}) {
+		t.Fatalf("unexpected deploy block %d (%s)", dc.DeployBlock, dc.DeployBlockHash)
+	}
+	if root := expected.HashTreeRoot(tree.GetHashFn()); dc.DepositRoot != root {
+		t.Fatalf("expected deposit root %s, got %s", root, dc.DepositRoot)
+	}
+	if _, err := LoadDepositContractLogs(logsPath, contract, 104); err == nil {
+		t.Fatal("expected error for logs after the execution-layer block")
+	}
+
+	// A plain list of logs works too, but the logs must be complete.
+	data, err = json.Marshal(append(logs[:2:2], logs[3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logsPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDepositContractLogs(logsPath, contract, 105); err == nil {
+		t.Fatal("expected error for missing deposit log")
+	}
+
+	// The genesis state continues from the deposits.
+	data, err = json.Marshal(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logsPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Build(context.Background(), &Options{
+		Spec:                    spec,
+		Fork:                    "capella",
+		Eth1BlockHash:           common.Root{1},
+		Validators:              validators[:64],
+		DepositContractLogsFile: logsPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eth1Data, err := res.State.Eth1Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eth1Data != dc.Eth1Data(common.Root{1}) {
+		t.Fatalf("unexpected eth1 data %+v", eth1Data)
+	}
+	if index, err := res.State.Eth1DepositIndex(); err != nil || index != 6 {
+		t.Fatalf("unexpected deposit index %d", index)
+	}
+
+	snapshot, err := res.DepositTreeSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 6 = 0b110: a subtree of 4 deposits, and one of 2 deposits
+	if len(snapshot.Finalized) != 2 || snapshot.DepositRoot != dc.DepositRoot || snapshot.DepositCount != 6 ||
+		snapshot.ExecutionBlockHash != (common.Root{1}) || snapshot.ExecutionBlockHeight != 0 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	if len(res.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %q", res.Warnings)
+	}
+
+	// Without deposit logs, the deploy block is not known.
+	if err := os.WriteFile(logsPath, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Build(context.Background(), &Options{
+		Spec:                    spec,
+		Fork:                    "capella",
+		Eth1BlockHash:           common.Root{1},
+		Validators:              validators[:64],
+		DepositContractLogsFile: logsPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "no deposit logs") {
+		t.Fatalf("unexpected warnings: %q", res.Warnings)
+	}
+}
+
+func TestFetchDepositContractState(t *testing.T) {
+	contract := common.Eth1Address{0x42}
+	blockHash := common.Root{0xbb}
+	var expected depositTree
+	for i := 0; i < 1234; i++ {
+		if err := expected.push(common.Root{byte(i), byte(i >> 8)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	depositRoot := expected.root()
+	const deployBlock = 321
+	// A pruned node does not have the state of the blocks before 400.
+	pruned := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		type request struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		var msg json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		// The storage is read in a batch of requests.
+		var reqs []request
+		batch := json.Unmarshal(msg, &reqs) == nil
+		if !batch {
+			reqs = make([]request, 1)
+			if err := json.Unmarshal(msg, &reqs[0]); err != nil {
+				http.Error(w, "unexpected request", http.StatusBadRequest)
+				return
+			}
+		}
+		var responses []any
+		for _, req := range reqs {
+			// The deploy block is searched by number, the contract is deployed in block 321 of 500.
+			switch req.Method {
+			case "eth_getBlockByHash", "eth_getBlockByNumber":
+				number := uint64(500)
+				if req.Method == "eth_getBlockByNumber" {
+					var n hexutil.Uint64
+					if err := json.Unmarshal(req.Params[0], &n); err != nil {
+						http.Error(w, "unexpected block number", http.StatusBadRequest)
+						return
+					}
+					number = uint64(n)
+				}
+				header := &types.Header{Number: new(big.Int).SetUint64(number), Difficulty: new(big.Int)}
+				responses = append(responses, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": header})
+				continue
+			case "eth_getCode":
+				var n hexutil.Uint64
+				if err := json.Unmarshal(req.Params[1], &n); err != nil {
+					http.Error(w, "unexpected block number", http.StatusBadRequest)
+					return
+				}
+				if pruned && n < 400 {
+					responses = append(responses, map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": map[string]any{"code": -32000, "message": "missing trie node"}})
+					continue
+				}
+				var code hexutil.Bytes
+				if n >= deployBlock {
+					code = hexutil.Bytes{0x60, 0x00}
+				}
+				responses = append(responses, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": code})
+				continue
+			}
+			var block struct {
+				BlockHash gethcommon.Hash `json:"blockHash"`
+			}
+			if len(req.Params) < 2 || json.Unmarshal(req.Params[len(req.Params)-1], &block) != nil || block.BlockHash != gethcommon.Hash(blockHash) {
+				http.Error(w, "unexpected block", http.StatusBadRequest)
+				return
+			}
+			var result []byte
+			switch req.Method {
+			case "eth_call":
+				var call struct {
+					To    gethcommon.Address `json:"to"`
+					Input hexutil.Bytes      `json:"input"`
+				}
+				if json.Unmarshal(req.Params[0], &call) != nil || call.To != gethcommon.Address(contract) {
+					http.Error(w, "unexpected call", http.StatusBadRequest)
+					return
+				}
+				switch string(call.Input) {
+				case string(getDepositRootSelector):
+					result = depositRoot[:]
+				case string(getDepositCountSelector):
+					result = abiEncodeBytes(binary.LittleEndian.AppendUint64(nil, expected.count))
+				}
+			case "eth_getStorageAt":
+				var slot gethcommon.Hash
+				if err := json.Unmarshal(req.Params[1], &slot); err != nil {
+					http.Error(w, "unexpected slot", http.StatusBadRequest)
+					return
+				}
+				index := slot.Big().Uint64()
+				if index < depositContractTreeDepth {
+					result = expected.branch[index][:]
+				} else {
+					result = gethcommon.BigToHash(new(big.Int).SetUint64(expected.count)).Bytes()
+				}
+			}
+			responses = append(responses, map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": hexutil.Bytes(result)})
+		}
+		if batch {
+			_ = json.NewEncoder(w).Encode(responses)
+		} else {
+			_ = json.NewEncoder(w).Encode(responses[0])
+		}
+	}))
+	defer srv.Close()
+
+	dc, err := FetchDepositContractState(context.Background(), srv.URL, contract, blockHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dc.DepositRoot != depositRoot || dc.DepositCount != 1234 {
+		t.Fatalf("unexpected deposit contract state %+v", dc)
+	}
+	deployHeader := &types.Header{Number: big.NewInt(deployBlock), Difficulty: new(big.Int)}
+	if dc.DeployBlock != deployBlock || dc.DeployBlockHash != common.Root(deployHeader.Hash()) {
+		t.Fatalf("unexpected deploy block %d (%s)", dc.DeployBlock, dc.DeployBlockHash)
+	}
+	// 1234 = 0b10011010010
+	if len(dc.Finalized) != 5 || dc.Finalized[0] != expected.branch[10] || dc.Finalized[4] != expected.branch[1] {
+		t.Fatalf("unexpected finalized roots %v", dc.Finalized)
+	}
+	if dc.deployBlockErr != nil {
+		t.Fatalf("unexpected deploy block error: %v", dc.deployBlockErr)
+	}
+
+	// The deploy block is not known without the state of older blocks.
+	pruned = true
+	dc, err = FetchDepositContractState(context.Background(), srv.URL, contract, blockHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dc.DeployBlockHash != (common.Root{}) || dc.deployBlockErr == nil || !strings.Contains(dc.deployBlockErr.Error(), "missing trie node") {
+		t.Fatalf("expected unknown deploy block, got %d (%s): %v", dc.DeployBlock, dc.DeployBlockHash, dc.deployBlockErr)
+	}
+	pruned = false
+
+	if _, err := FetchDepositContractState(context.Background(), srv.URL, common.Eth1Address{1}, blockHash); err == nil {
+		t.Fatal("expected error for call to other contract")
+	}
+
+	// The branch in storage must match the deposit root.
+	expected.branch[1][0] ^= 1
+	if _, err := FetchDepositContractState(context.Background(), srv.URL, contract, blockHash); err == nil {
+		t.Fatal("expected error for branch that does not match the deposit root")
+	}
+}
@@ -0,0 +1,171 @@
+package genesis
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// depositDataEntry is an entry of a deposit_data-*.json file, as written by the staking-deposit-cli.
+// Byte fields are hex-encoded, with or without 0x prefix.
+type depositDataEntry struct {
+	Pubkey                string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawal_credentials"`
+	Amount                uint64 `json:"amount"`
+	Signature             string `json:"signature"`
+	DepositMessageRoot    string `json:"deposit_message_root"`
+	DepositDataRoot       string `json:"deposit_data_root"`
+	ForkVersion           string `json:"fork_version"`
+}
+
+// DepositDataProblem describes why a deposit data entry was rejected.
+type DepositDataProblem struct {
+	Path   string
+	Index  int
+	Pubkey string
+	Err    error
+}
+
+func (p *DepositDataProblem) Error() string {
+	return fmt.Sprintf("%s entry %d (pubkey %s): %v", p.Path, p.Index, p.Pubkey, p.Err)
+}
+
+// LoadDepositData reads validators from deposit_data JSON files.
+// Each deposit must have a valid signature for the GENESIS_FORK_VERSION deposit domain, a matching deposit_data_root,
+// and withdrawal credentials of a type the fork of the genesis state supports (any known type if the fork is nil).
+// Invalid entries, and repeated deposits of the same pubkey, are not loaded but reported as problems.
+func LoadDepositData(spec *common.Spec, fork *Fork, paths []string) ([]phase0.KickstartValidatorData, []*DepositDataProblem, error) {
+	validators, _, problems, err := loadDepositData(spec, fork, paths)
+	return validators, problems, err
+}
+
+// loadDepositData also returns the file and entry of each validator.
+func loadDepositData(spec *common.Spec, fork *Fork, paths []string) ([]phase0.KickstartValidatorData, []string, []*DepositDataProblem, error) {
+	var validators []phase0.KickstartValidatorData
+	var entryNames []string
+	var problems []*DepositDataProblem
+	seen := make(map[common.BLSPubkey]string)
+	domain := common.ComputeDomain(common.DOMAIN_DEPOSIT, spec.GENESIS_FORK_VERSION, common.Root{})
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read deposit data: %w", err)
+		}
+		var entries []depositDataEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to decode deposit data %s: %w", path, err)
+		}
+		for i, entry := range entries {
+			dep, err := entry.verify(spec, fork, domain)
+			if err == nil {
+				if other, ok := seen[dep.Pubkey]; ok {
+					err = fmt.Errorf("duplicate pubkey, already deposited in %s", other)
+				}
+			}
+			if err != nil {
+				problems = append(problems, &DepositDataProblem{Path: path, Index: i, Pubkey: entry.Pubkey, Err: err})
+				continue
+			}
+			entryName := fmt.Sprintf("%s entry %d", path, i)
+			seen[dep.Pubkey] = entryName
+			entryNames = append(entryNames, entryName)
+			validators = append(validators, phase0.KickstartValidatorData{
+				Pubkey:                dep.Pubkey,
+				WithdrawalCredentials: dep.WithdrawalCredentials,
+				Balance:               dep.Amount,
+			})
+		}
+	}
+	return validators, entryNames, problems, nil
+}
+
+func (entry *depositDataEntry) verify(spec *common.Spec, fork *Fork, domain common.BLSDomain) (*common.DepositData, error) {
+	var dep common.DepositData
+	if err := decodeHexField("pubkey", entry.Pubkey, dep.Pubkey[:]); err != nil {
+		return nil, err
+	}
+	if err := decodeHexField("withdrawal_credentials", entry.WithdrawalCredentials, dep.WithdrawalCredentials[:]); err != nil {
+		return nil, err
+	}
+	if err := decodeHexField("signature", entry.Signature, dep.Signature[:]); err != nil {
+		return nil, err
+	}
+	switch prefix := dep.WithdrawalCredentials[0]; prefix {
+	case common.BLS_WITHDRAWAL_PREFIX:
+	case common.ETH1_ADDRESS_WITHDRAWAL_PREFIX, COMPOUNDING_WITHDRAWAL_PREFIX:
+		if prefix == COMPOUNDING_WITHDRAWAL_PREFIX && fork != nil && !fork.AtLeast("electra") {
+			return nil, fmt.Errorf("compounding withdrawal credentials are not supported before electra, the genesis fork is %s", fork.Name)
+		}
+		if [11]byte(dep.WithdrawalCredentials[1:12]) != ([11]byte{}) {
+			return nil, fmt.Errorf("invalid 0x%02x withdrawal credentials %s, bytes 1 to 11 must be zero", prefix, dep.WithdrawalCredentials)
+		}
+	default:
+		return nil, fmt.Errorf("unknown withdrawal credentials prefix 0x%02x", prefix)
+	}
+	dep.Amount = common.Gwei(entry.Amount)
+	if dep.Amount < spec.MIN_DEPOSIT_AMOUNT {
+		return nil, fmt.Errorf("amount %d is less than MIN_DEPOSIT_AMOUNT %d", dep.Amount, spec.MIN_DEPOSIT_AMOUNT)
+	}
+	if entry.ForkVersion != "" {
+		var version common.Version
+		if err := decodeHexField("fork_version", entry.ForkVersion, version[:]); err != nil {
+			return nil, err
+		}
+		if version != spec.GENESIS_FORK_VERSION {
+			return nil, fmt.Errorf("fork version %s does not match GENESIS_FORK_VERSION %s", version, spec.GENESIS_FORK_VERSION)
+		}
+	}
+
+	hFn := tree.GetHashFn()
+	msgRoot := dep.ToMessage().HashTreeRoot(hFn)
+	if entry.DepositMessageRoot != "" {
+		var expected common.Root
+		if err := decodeHexField("deposit_message_root", entry.DepositMessageRoot, expected[:]); err != nil {
+			return nil, err
+		}
+		if msgRoot != expected {
+			return nil, fmt.Errorf("deposit_message_root %s does not match computed root %s", expected, msgRoot)
+		}
+	}
+
+	var pub blsu.Pubkey
+	if err := pub.Deserialize((*[48]byte)(&dep.Pubkey)); err != nil {
+		return nil, fmt.Errorf("invalid pubkey: %w", err)
+	}
+	var sig blsu.Signature
+	if err := sig.Deserialize((*[96]byte)(&dep.Signature)); err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	signingRoot := common.ComputeSigningRoot(msgRoot, domain)
+	if !blsu.Verify(&pub, signingRoot[:], &sig) {
+		return nil, fmt.Errorf("invalid deposit signature")
+	}
+	var expectedDataRoot common.Root
+	if err := decodeHexField("deposit_data_root", entry.DepositDataRoot, expectedDataRoot[:]); err != nil {
+		return nil, err
+	}
+	if dataRoot := dep.HashTreeRoot(hFn); dataRoot != expectedDataRoot {
+		return nil, fmt.Errorf("deposit_data_root %s does not match computed root %s", expectedDataRoot, dataRoot)
+	}
+	return &dep, nil
+}
+
+func decodeHexField(name string, value string, dst []byte) error {
+	b, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("invalid %s: expected %d bytes, got %d", name, len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
@@ -0,0 +1,158 @@
+package genesis
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// testDepositData signs a deposit like the staking-deposit-cli, with insecure secret key i+1.
+func testDepositData(t *testing.T, spec *common.Spec, i int) depositDataEntry {
+	return testDepositDataWithCredentials(t, spec, i, common.Root{0x01, 31: byte(i)})
+}
+
+// testDepositDataWithCredentials is a signed deposit data entry of the i-th test key, with the withdrawal credentials.
+func testDepositDataWithCredentials(t *testing.T, spec *common.Spec, i int, withdrawalCredentials common.Root) depositDataEntry {
+	var skBytes [32]byte
+	binary.BigEndian.PutUint64(skBytes[24:], uint64(i+1))
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&skBytes); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := blsu.SkToPk(&sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dep := common.DepositData{
+		Pubkey:                pub.Serialize(),
+		WithdrawalCredentials: withdrawalCredentials,
+		Amount:                spec.MAX_EFFECTIVE_BALANCE,
+	}
+	hFn := tree.GetHashFn()
+	msgRoot := dep.ToMessage().HashTreeRoot(hFn)
+	domain := common.ComputeDomain(common.DOMAIN_DEPOSIT, spec.GENESIS_FORK_VERSION, common.Root{})
+	signingRoot := common.ComputeSigningRoot(msgRoot, domain)
+	dep.Signature = blsu.Sign(&sk, signingRoot[:]).Serialize()
+	dataRoot := dep.HashTreeRoot(hFn)
+	return depositDataEntry{
+		Pubkey:                hex.EncodeToString(dep.Pubkey[:]),
+		WithdrawalCredentials: hex.EncodeToString(dep.WithdrawalCredentials[:]),
+		Amount:                uint64(dep.Amount),
+		Signature:             hex.EncodeToString(dep.Signature[:]),
+		DepositMessageRoot:    hex.EncodeToString(msgRoot[:]),
+		DepositDataRoot:       hex.EncodeToString(dataRoot[:]),
+		ForkVersion:           hex.EncodeToString(spec.GENESIS_FORK_VERSION[:]),
+	}
+}
+
+func writeDepositData(t *testing.T, path string, entries []depositDataEntry) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDepositData(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+
+	entries := make([]depositDataEntry, 6)
+	for i := range entries {
+		entries[i] = testDepositData(t, spec, i)
+	}
+	// signed by another key
+	entries[1].Signature = entries[0].Signature
+	// amount changed after signing
+	entries[2].Amount = 1_000_000_000
+	// root does not match
+	entries[3].DepositDataRoot = entries[4].DepositDataRoot
+	// signed for another network
+	entries[4].ForkVersion = "01020304"
+	first := filepath.Join(dir, "deposit_data-1.json")
+	writeDepositData(t, first, entries)
+	// entry 5 is deposited twice
+	second := filepath.Join(dir, "deposit_data-2.json")
+	writeDepositData(t, second, []depositDataEntry{entries[5], testDepositData(t, spec, 6)})
+
+	validators, problems, err := LoadDepositData(spec, nil, []string{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 3 {
+		t.Fatalf("expected 3 validators, got %d", len(validators))
+	}
+	if validators[0].Balance != spec.MAX_EFFECTIVE_BALANCE || validators[0].WithdrawalCredentials != (common.Root{0x01}) {
+		t.Fatalf("unexpected validator: %+v", validators[0])
+	}
+	expected := []struct {
+		path  string
+		index int
+		err   string
+	}{
+		{first, 1, "invalid deposit signature"},
+		{first, 2, "deposit_message_root"},
+		{first, 3, "deposit_data_root"},
+		{first, 4, "fork version"},
+		{second, 0, "duplicate pubkey"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, exp := range expected {
+		p := problems[i]
+		if p.Path != exp.path || p.Index != exp.index || !strings.Contains(p.Err.Error(), exp.err) {
+			t.Errorf("problem %d: expected %s entry %d: %s, got: %s", i, exp.path, exp.index, exp.err, p)
+		}
+	}
+}
+
+func TestLoadDepositDataWithdrawalCredentials(t *testing.T) {
+	spec := configs.Minimal
+	path := filepath.Join(t.TempDir(), "deposit_data.json")
+	writeDepositData(t, path, []depositDataEntry{
+		testDepositDataWithCredentials(t, spec, 0, common.Root{0x00, 31: 1}),
+		testDepositDataWithCredentials(t, spec, 1, common.Root{0x01, 31: 1}),
+		testDepositDataWithCredentials(t, spec, 2, common.Root{COMPOUNDING_WITHDRAWAL_PREFIX, 31: 1}),
+		testDepositDataWithCredentials(t, spec, 3, common.Root{0x03, 31: 1}),
+		testDepositDataWithCredentials(t, spec, 4, common.Root{0x01, 1: 1, 31: 1}),
+	})
+	for _, tc := range []struct {
+		fork     string
+		rejected map[int]string
+	}{
+		{"deneb", map[int]string{2: "not supported before electra", 3: "unknown withdrawal credentials prefix 0x03", 4: "bytes 1 to 11 must be zero"}},
+		{"electra", map[int]string{3: "unknown withdrawal credentials prefix 0x03", 4: "bytes 1 to 11 must be zero"}},
+	} {
+		t.Run(tc.fork, func(t *testing.T) {
+			fork, err := ForkByName(tc.fork)
+			if err != nil {
+				t.Fatal(err)
+			}
+			validators, problems, err := LoadDepositData(spec, fork, []string{path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(validators) != 5-len(tc.rejected) || len(problems) != len(tc.rejected) {
+				t.Fatalf("expected %d rejected deposits, got %d validators and problems %v", len(tc.rejected), len(validators), problems)
+			}
+			for _, p := range problems {
+				if expected, ok := tc.rejected[p.Index]; !ok || !strings.Contains(p.Err.Error(), expected) {
+					t.Errorf("unexpected problem: %s", p)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package genesis
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// DepositTreeSnapshot is the EIP-4881 snapshot of the deposit tree, at the execution-layer block of the genesis state.
+// Clients start their deposit tree from it, instead of scanning the deposit contract logs from the deployment block.
+type DepositTreeSnapshot struct {
+	Finalized            DepositSnapshotRoots `json:"finalized" yaml:"finalized"`
+	DepositRoot          common.Root          `json:"deposit_root" yaml:"deposit_root"`
+	DepositCount         view.Uint64View      `json:"deposit_count" yaml:"deposit_count"`
+	ExecutionBlockHash   common.Root          `json:"execution_block_hash" yaml:"execution_block_hash"`
+	ExecutionBlockHeight view.Uint64View      `json:"execution_block_height" yaml:"execution_block_height"`
+}
+
+// DepositSnapshotRoots are the finalized roots of a deposit tree snapshot, List[Hash32, DEPOSIT_CONTRACT_DEPTH] in SSZ.
+type DepositSnapshotRoots []common.Root
+
+func (r DepositSnapshotRoots) Serialize(w *codec.EncodingWriter) error {
+	for i := range r {
+		if err := w.Write(r[i][:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r DepositSnapshotRoots) ByteLength() uint64 {
+	return uint64(len(r)) * 32
+}
+
+func (r DepositSnapshotRoots) FixedLength() uint64 {
+	return 0
+}
+
+func (s *DepositTreeSnapshot) Serialize(w *codec.EncodingWriter) error {
+	return w.Container(s.Finalized, &s.DepositRoot, s.DepositCount, &s.ExecutionBlockHash, s.ExecutionBlockHeight)
+}
+
+func (s *DepositTreeSnapshot) ByteLength() uint64 {
+	return codec.ContainerLength(s.Finalized, &s.DepositRoot, s.DepositCount, &s.ExecutionBlockHash, s.ExecutionBlockHeight)
+}
+
+func (s *DepositTreeSnapshot) FixedLength() uint64 {
+	return 0
+}
+
+// CalculateRoot computes the deposit root from the finalized roots and the deposit count, like calculate_root of EIP-4881.
+func (s *DepositTreeSnapshot) CalculateRoot() common.Root {
+	size := uint64(s.DepositCount)
+	index := len(s.Finalized)
+	var root common.Root
+	for height := 0; height < depositContractTreeDepth; height++ {
+		if size&1 == 1 {
+			index--
+			if index < 0 {
+				// Not enough finalized roots for the count, the snapshot is invalid.
+				return common.Root{}
+			}
+			root = sha256.Sum256(append(s.Finalized[index][:], root[:]...))
+		} else {
+			root = sha256.Sum256(append(root[:], tree.ZeroHashes[height][:]...))
+		}
+		size >>= 1
+	}
+	var count common.Root
+	binary.LittleEndian.PutUint64(count[:], uint64(s.DepositCount))
+	return sha256.Sum256(append(root[:], count[:]...))
+}
+
+// DepositTreeSnapshot returns the deposit tree snapshot of the eth1 data of the genesis state:
+// the deposit tree of the deposit contract that the state continues from, or else the empty deposit tree.
+// The execution block height is 0 if there is no execution-layer block.
+func (r *Result) DepositTreeSnapshot() (*DepositTreeSnapshot, error) {
+	eth1Data, err := r.State.Eth1Data()
+	if err != nil {
+		return nil, err
+	}
+	s := &DepositTreeSnapshot{
+		Finalized:          DepositSnapshotRoots{},
+		DepositRoot:        eth1Data.DepositRoot,
+		DepositCount:       view.Uint64View(eth1Data.DepositCount),
+		ExecutionBlockHash: eth1Data.BlockHash,
+	}
+	if r.DepositContract != nil {
+		s.Finalized = r.DepositContract.Finalized
+	}
+	if r.Eth1Block != nil {
+		s.ExecutionBlockHeight = view.Uint64View(r.Eth1Block.NumberU64())
+	}
+	if len(s.Finalized) > depositContractTreeDepth {
+		return nil, fmt.Errorf("%d finalized deposit roots, the limit is %d", len(s.Finalized), depositContractTreeDepth)
+	}
+	if root := s.CalculateRoot(); root != s.DepositRoot {
+		return nil, fmt.Errorf("deposit root %s of the finalized roots does not match the eth1 data deposit root %s", root, s.DepositRoot)
+	}
+	return s, nil
+}
+
+// WriteDepositTreeSnapshot writes the snapshot as SSZ if the path ends with ".ssz", or else as JSON,
+// like the data of the /eth/v1/beacon/deposit_snapshot Beacon API.
+func WriteDepositTreeSnapshot(path string, s *DepositTreeSnapshot) error {
+	var data []byte
+	if strings.HasSuffix(path, ".ssz") {
+		var buf bytes.Buffer
+		if err := s.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	} else {
+		var err error
+		data, err = json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write deposit tree snapshot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// depositTreeSnapshotType is the SSZ type of the EIP-4881 DepositTreeSnapshot, to check the encoding with.
+var depositTreeSnapshotType = view.ContainerType("DepositTreeSnapshot", []view.FieldDef{
+	{Name: "finalized", Type: view.ComplexListType(view.RootType, depositContractTreeDepth)},
+	{Name: "deposit_root", Type: view.RootType},
+	{Name: "deposit_count", Type: view.Uint64Type},
+	{Name: "execution_block_hash", Type: view.RootType},
+	{Name: "execution_block_height", Type: view.Uint64Type},
+})
+
+func TestDepositTreeSnapshot(t *testing.T) {
+	spec := configs.Minimal
+	res, err := Build(context.Background(), &Options{
+		Spec:          spec,
+		Fork:          "phase0",
+		Eth1BlockHash: common.Root{1},
+		Validators:    testValidators(t, spec, 64),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a deposit contract state, the snapshot is of the empty deposit tree.
+	snapshot, err := res.DepositTreeSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Finalized) != 0 || snapshot.DepositCount != 0 ||
+		snapshot.DepositRoot != phase0.NewDepositRootsView().HashTreeRoot(tree.GetHashFn()) {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+
+	var deposits depositTree
+	for i := 0; i < 11; i++ {
+		if err := deposits.push(common.Root{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot = &DepositTreeSnapshot{
+		Finalized:            deposits.finalized(),
+		DepositRoot:          deposits.root(),
+		DepositCount:         11,
+		ExecutionBlockHash:   common.Root{2},
+		ExecutionBlockHeight: 42,
+	}
+	if snapshot.CalculateRoot() != snapshot.DepositRoot {
+		t.Fatal("deposit root does not match the finalized roots")
+	}
+
+	dir := t.TempDir()
+	sszPath := filepath.Join(dir, "snapshot.ssz")
+	if err := WriteDepositTreeSnapshot(sszPath, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(sszPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := view.AsContainer(depositTreeSnapshotType.Deserialize(codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	finalized, err := view.AsComplexList(decoded.Get(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := finalized.Length(); err != nil || n != 3 {
+		t.Fatalf("unexpected finalized roots length %d", n)
+	}
+	if root, err := view.AsRoot(decoded.Get(1)); err != nil || root != snapshot.DepositRoot {
+		t.Fatalf("unexpected deposit root %s", root)
+	}
+	height, err := view.AsUint64(decoded.Get(4))
+	if err != nil || height != 42 {
+		t.Fatalf("unexpected execution block height %d", height)
+	}
+
+	jsonPath := filepath.Join(dir, "snapshot.json")
+	if err := WriteDepositTreeSnapshot(jsonPath, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["deposit_count"] != "11" || fields["execution_block_height"] != "42" {
+		t.Fatalf("unexpected JSON snapshot %s", data)
+	}
+}
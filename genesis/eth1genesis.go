@@ -0,0 +1,195 @@
+package genesis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// The deposit contract keeps its zero hashes in storage slots 33 to 64 (zero_hashes[0] is zero, so not stored),
+// after the 32 branch slots and the deposit_count slot.
+const (
+	depositContractTreeDepth      = 32
+	depositContractZeroHashesSlot = 33
+)
+
+// DefaultEth1GasLimit is the gas limit of generated execution-layer genesis blocks, if the template does not set one.
+const DefaultEth1GasLimit = 36_000_000
+
+// DepositContractStorage is the storage of a freshly deployed deposit contract, with an empty deposit tree,
+// as computed by the deposit contract constructor.
+func DepositContractStorage() map[gethcommon.Hash]gethcommon.Hash {
+	storage := make(map[gethcommon.Hash]gethcommon.Hash)
+	var zeroHash [32]byte
+	for height := 0; height < depositContractTreeDepth-1; height++ {
+		zeroHash = sha256.Sum256(append(zeroHash[:], zeroHash[:]...))
+		slot := gethcommon.BigToHash(big.NewInt(int64(depositContractZeroHashesSlot + height + 1)))
+		storage[slot] = zeroHash
+	}
+	return storage
+}
+
+// LoadDepositContractCode reads the hex-encoded deployed (runtime) bytecode of the deposit contract.
+func LoadDepositContractCode(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deposit contract code: %w", err)
+	}
+	code, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode deposit contract code: %w", err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("deposit contract code %s is empty", path)
+	}
+	return code, nil
+}
+
+// systemContracts are the execution-layer system contracts, predeployed when the execution-layer fork
+// that introduces them is scheduled.
+var systemContracts = []struct {
+	Eth1ForkName string
+	Address      gethcommon.Address
+	Code         []byte
+}{
+	{"cancun", params.BeaconRootsAddress, params.BeaconRootsCode},               // EIP-4788
+	{"prague", params.HistoryStorageAddress, params.HistoryStorageCode},         // EIP-2935
+	{"prague", params.WithdrawalQueueAddress, params.WithdrawalQueueCode},       // EIP-7002
+	{"prague", params.ConsolidationQueueAddress, params.ConsolidationQueueCode}, // EIP-7251
+}
+
+// GenerateEth1Genesis creates the execution-layer genesis that matches the consensus-layer config,
+// starting from a template genesis.json with the alloc, gas limit and other execution-layer settings.
+//
+// The chain ID, deposit contract address, terminal total difficulty, blob schedule and fork times
+// of the template are overwritten: forks at epoch 0 are active at genesis, later forks are scheduled
+// at the start of their epoch, counted from the beacon genesis time (eth1Timestamp + GENESIS_DELAY).
+//
+// The deposit contract is deployed with depositContractCode, and with the storage of an empty deposit tree.
+// depositContractCode may be nil if the template already has the deposit contract code.
+// The EIP-4788, EIP-2935, EIP-7002 and EIP-7251 system contracts are added if not in the template.
+func GenerateEth1Genesis(spec *common.Spec, template *core.Genesis, eth1Timestamp common.Timestamp, depositContractCode []byte) (*core.Genesis, error) {
+	g := *template
+	var cfg params.ChainConfig
+	if template.Config != nil {
+		cfg = *template.Config
+	}
+	g.Config = &cfg
+	g.Timestamp = uint64(eth1Timestamp)
+
+	cfg.ChainID = new(big.Int).SetUint64(uint64(spec.DEPOSIT_CHAIN_ID))
+	cfg.DepositContractAddress = gethcommon.Address(spec.DEPOSIT_CONTRACT_ADDRESS)
+	// All pre-merge upgrades are active at genesis
+	zero := big.NewInt(0)
+	cfg.HomesteadBlock = zero
+	cfg.EIP150Block = zero
+	cfg.EIP155Block = zero
+	cfg.EIP158Block = zero
+	cfg.ByzantiumBlock = zero
+	cfg.ConstantinopleBlock = zero
+	cfg.PetersburgBlock = zero
+	cfg.IstanbulBlock = zero
+	cfg.MuirGlacierBlock = zero
+	cfg.BerlinBlock = zero
+	cfg.LondonBlock = zero
+	cfg.ArrowGlacierBlock = zero
+	cfg.GrayGlacierBlock = zero
+
+	switch spec.BELLATRIX_FORK_EPOCH {
+	case common.GENESIS_EPOCH:
+		cfg.TerminalTotalDifficulty = big.NewInt(0)
+		cfg.MergeNetsplitBlock = zero
+		g.Difficulty = big.NewInt(0)
+	case common.FAR_FUTURE_EPOCH:
+		cfg.TerminalTotalDifficulty = nil
+		cfg.MergeNetsplitBlock = nil
+	default:
+		ttd := uint256.Int(spec.TERMINAL_TOTAL_DIFFICULTY)
+		cfg.TerminalTotalDifficulty = ttd.ToBig()
+		cfg.MergeNetsplitBlock = nil
+	}
+
+	genesisTime := uint64(eth1Timestamp + spec.GENESIS_DELAY)
+	epochDuration := uint64(spec.SLOTS_PER_EPOCH) * uint64(spec.SECONDS_PER_SLOT)
+	scheduled := make(map[string]bool)
+	for _, f := range Forks {
+		if f.SetEth1ForkTime == nil {
+			continue
+		}
+		switch epoch := f.Epoch(spec); epoch {
+		case common.GENESIS_EPOCH:
+			f.SetEth1ForkTime(&cfg, new(uint64))
+			scheduled[f.Eth1ForkName] = true
+		case common.FAR_FUTURE_EPOCH:
+			f.SetEth1ForkTime(&cfg, nil)
+		default:
+			t := genesisTime + uint64(epoch)*epochDuration
+			f.SetEth1ForkTime(&cfg, &t)
+			scheduled[f.Eth1ForkName] = true
+		}
+	}
+
+	// Like the defaults, but with the max blobs of the consensus-layer config.
+	blobConfig := func(defaultConfig *params.BlobConfig, maxBlobs uint64) *params.BlobConfig {
+		c := *defaultConfig
+		c.Max = int(maxBlobs)
+		if c.Target > c.Max {
+			c.Target = c.Max
+		}
+		return &c
+	}
+	var blobSchedule params.BlobScheduleConfig
+	if cfg.BlobScheduleConfig != nil {
+		blobSchedule = *cfg.BlobScheduleConfig
+	}
+	if scheduled["cancun"] {
+		blobSchedule.Cancun = blobConfig(params.DefaultCancunBlobConfig, uint64(spec.MAX_BLOBS_PER_BLOCK))
+	}
+	if scheduled["prague"] {
+		blobSchedule.Prague = blobConfig(params.DefaultPragueBlobConfig, uint64(spec.MAX_BLOBS_PER_BLOCK_ELECTRA))
+	}
+	cfg.BlobScheduleConfig = &blobSchedule
+
+	alloc := make(types.GenesisAlloc, len(template.Alloc)+len(systemContracts)+1)
+	for addr, acc := range template.Alloc {
+		alloc[addr] = acc
+	}
+	depositContract := alloc[cfg.DepositContractAddress]
+	if depositContractCode != nil {
+		depositContract.Code = depositContractCode
+	}
+	if len(depositContract.Code) == 0 {
+		return nil, fmt.Errorf("no deposit contract code for %s in template, and no deposit contract code given", cfg.DepositContractAddress)
+	}
+	if len(depositContract.Storage) == 0 {
+		depositContract.Storage = DepositContractStorage()
+	}
+	if depositContract.Balance == nil {
+		depositContract.Balance = big.NewInt(0)
+	}
+	alloc[cfg.DepositContractAddress] = depositContract
+
+	for _, c := range systemContracts {
+		if _, ok := alloc[c.Address]; ok || !scheduled[c.Eth1ForkName] {
+			continue
+		}
+		alloc[c.Address] = types.Account{Nonce: 1, Code: c.Code, Balance: big.NewInt(0)}
+	}
+	g.Alloc = alloc
+
+	if g.GasLimit == 0 {
+		g.GasLimit = DefaultEth1GasLimit
+	}
+	return &g, nil
+}
@@ -0,0 +1,94 @@
+package genesis
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestDepositContractStorage(t *testing.T) {
+	storage := DepositContractStorage()
+	if len(storage) != 31 {
+		t.Fatalf("expected 31 zero hashes, got %d", len(storage))
+	}
+	// zero_hashes[1] = sha256(32 zero bytes ++ 32 zero bytes)
+	if v := storage[gethcommon.HexToHash("0x22")]; v != gethcommon.HexToHash("0xf5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b") {
+		t.Fatalf("unexpected zero_hashes[1]: %s", v)
+	}
+	if _, ok := storage[gethcommon.HexToHash("0x40")]; !ok {
+		t.Fatal("expected zero_hashes[31] in slot 0x40")
+	}
+}
+
+func TestGenerateEth1Genesis(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ELECTRA_FORK_EPOCH = 2
+	spec.FULU_FORK_EPOCH = common.FAR_FUTURE_EPOCH
+
+	funded := gethcommon.HexToAddress("0x1234")
+	template := &core.Genesis{
+		Alloc: types.GenesisAlloc{
+			funded: {Balance: big.NewInt(1e18)},
+		},
+	}
+	if _, err := GenerateEth1Genesis(&spec, template, 1000, nil); err == nil {
+		t.Fatal("expected error without deposit contract code")
+	}
+	depositContractCode := []byte{0x60, 0x00}
+	g, err := GenerateEth1Genesis(&spec, template, 1000, depositContractCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Timestamp != 1000 {
+		t.Fatalf("unexpected timestamp %d", g.Timestamp)
+	}
+	if g.Config.OsakaTime != nil {
+		t.Fatal("expected osaka to not be scheduled")
+	}
+	if g.Config.PragueTime == nil || *g.Config.PragueTime != 1000+uint64(spec.GENESIS_DELAY)+2*8*6 {
+		t.Fatalf("unexpected prague time %v", g.Config.PragueTime)
+	}
+	depositContract := g.Alloc[gethcommon.Address(spec.DEPOSIT_CONTRACT_ADDRESS)]
+	if string(depositContract.Code) != string(depositContractCode) || len(depositContract.Storage) != 31 {
+		t.Fatal("unexpected deposit contract")
+	}
+	for _, addr := range []gethcommon.Address{funded, params.BeaconRootsAddress, params.HistoryStorageAddress,
+		params.WithdrawalQueueAddress, params.ConsolidationQueueAddress} {
+		if _, ok := g.Alloc[addr]; !ok {
+			t.Errorf("missing alloc for %s", addr)
+		}
+	}
+	if len(template.Alloc) != 1 || template.Config != nil {
+		t.Fatal("template was modified")
+	}
+
+	// The generated genesis passes the config checks, and can be used to create the matching beacon state.
+	res, err := Build(context.Background(), &Options{
+		Spec:                 &spec,
+		Fork:                 "deneb",
+		Eth1Genesis:          g,
+		MatchEth1GenesisTime: true,
+		Validators:           testValidators(t, &spec, 64),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Eth1Block.Hash() != g.ToBlock().Hash() {
+		t.Fatal("expected execution-layer genesis block in state")
+	}
+	if m := res.Metadata(); m.ShadowFork || m.Eth1Block == nil || m.Eth1Block.Number != 0 || m.Eth1Block.Hash != common.Root(res.Eth1Block.Hash()) {
+		t.Fatalf("unexpected metadata: %+v", m)
+	}
+}
@@ -0,0 +1,157 @@
+package genesis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type JSONData struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type rpcBlock struct {
+	Hash         common.Hash         `json:"hash"`
+	Transactions []rpcTransaction    `json:"transactions"`
+	UncleHashes  []common.Hash       `json:"uncles"`
+	Withdrawals  []*types.Withdrawal `json:"withdrawals,omitempty"`
+}
+
+type rpcTransaction struct {
+	tx *types.Transaction
+	txExtraInfo
+}
+
+type txExtraInfo struct {
+	BlockNumber *string         `json:"blockNumber,omitempty"`
+	BlockHash   *common.Hash    `json:"blockHash,omitempty"`
+	From        *common.Address `json:"from,omitempty"`
+}
+
+func (tx *rpcTransaction) UnmarshalJSON(msg []byte) error {
+	if err := json.Unmarshal(msg, &tx.tx); err != nil {
+		return err
+	}
+	return json.Unmarshal(msg, &tx.txExtraInfo)
+}
+
+func ParseEthBlock(blockData json.RawMessage) (*types.Block, error) {
+	var resultHeader types.Header
+	if err := json.Unmarshal(blockData, &resultHeader); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON header: %w", err)
+	}
+
+	var body rpcBlock
+	if err := json.Unmarshal(blockData, &body); err != nil {
+		return nil, err
+	}
+
+	// get transactions
+	txs := make([]*types.Transaction, len(body.Transactions))
+	for idx := range body.Transactions {
+		txs[idx] = body.Transactions[idx].tx
+	}
+
+	return types.NewBlockWithHeader(&resultHeader).WithBody(types.Body{
+		Transactions: txs,
+		Uncles:       nil,
+		Withdrawals:  body.Withdrawals,
+	}), nil
+}
+
+// LoadEth1Block loads the execution-layer block to embed in the genesis state,
+// and the prev-randao value to put in the execution payload header.
+// The shadow-fork block file takes precedence over the shadow-fork RPC, which takes precedence over the genesis config.
+// The shadowForkBlock selects the block to fetch from the shadow-fork RPC, see ParseBlockSelector.
+// A nil block is returned if there is no execution-layer block source at all.
+func LoadEth1Block(ctx context.Context, shadowForkBlockFile string, shadowForkEth1RPC string, shadowForkBlock string, eth1Genesis *core.Genesis) (*types.Block, [32]byte, error) {
+	if shadowForkBlock != "" && (shadowForkEth1RPC == "" || shadowForkBlockFile != "") {
+		return nil, [32]byte{}, fmt.Errorf("the shadow-fork block %q can only be selected with a shadow-fork RPC, and no shadow-fork block file", shadowForkBlock)
+	}
+	if shadowForkBlockFile != "" {
+		// Read the JSON file from disk
+		file, err := os.ReadFile(shadowForkBlockFile)
+		if err != nil {
+			return nil, [32]byte{}, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		// Unmarshal the JSON into a types.Block object
+		var resultData JSONData
+		if err := json.Unmarshal(file, &resultData); err != nil {
+			return nil, [32]byte{}, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+
+		eth1Block, err := ParseEthBlock(resultData.Result)
+		if err != nil {
+			return nil, [32]byte{}, fmt.Errorf("failed to parse eth1 block: %w", err)
+		}
+
+		// Convert and set the difficulty as the prevRandao field
+		return eth1Block, bigIntToBytes32(eth1Block.Difficulty()), nil
+	} else if shadowForkEth1RPC != "" {
+		number, hash, err := ParseBlockSelector(shadowForkBlock)
+		if err != nil {
+			return nil, [32]byte{}, err
+		}
+		client, err := ethclient.Dial(shadowForkEth1RPC)
+		if err != nil {
+			return nil, [32]byte{}, fmt.Errorf("A fatal error occurred creating the ETH client %s", err)
+		}
+		defer client.Close()
+
+		var eth1Block *types.Block
+		if hash != nil {
+			eth1Block, err = client.BlockByHash(ctx, *hash)
+		} else {
+			// A single request, so the block is consistent even if new blocks are coming in
+			eth1Block, err = client.BlockByNumber(ctx, number)
+		}
+		if err != nil {
+			return nil, [32]byte{}, fmt.Errorf("A fatal error occurred getting the ETH block %q: %s", shadowForkBlock, err)
+		}
+
+		// Convert and set the difficulty as the prevRandao field
+		return eth1Block, bigIntToBytes32(eth1Block.Difficulty()), nil
+	} else if eth1Genesis != nil {
+		// Generate genesis block from the loaded config, with default prevRandao
+		return eth1Genesis.ToBlock(), [32]byte{}, nil
+	}
+	return nil, [32]byte{}, nil
+}
+
+// ParseBlockSelector parses a block number (decimal or 0x-prefixed hex), a 32-byte block hash,
+// or one of the "latest", "safe" and "finalized" tags. An empty selector is the latest block.
+// Either the number or the hash is returned. A nil number is the latest block.
+func ParseBlockSelector(selector string) (number *big.Int, hash *common.Hash, err error) {
+	switch selector {
+	case "", "latest":
+		return nil, nil, nil
+	case "safe":
+		return big.NewInt(int64(rpc.SafeBlockNumber)), nil, nil
+	case "finalized":
+		return big.NewInt(int64(rpc.FinalizedBlockNumber)), nil, nil
+	}
+	if strings.HasPrefix(selector, "0x") && len(selector) == 2+2*common.HashLength {
+		h := common.HexToHash(selector)
+		if !strings.EqualFold(h.Hex(), selector) {
+			return nil, nil, fmt.Errorf("invalid block hash %q", selector)
+		}
+		return nil, &h, nil
+	}
+	number, ok := new(big.Int).SetString(selector, 0)
+	if !ok || number.Sign() < 0 || !number.IsUint64() {
+		return nil, nil, fmt.Errorf("invalid block %q, expected a block number, block hash, 'latest', 'safe' or 'finalized'", selector)
+	}
+	return number, nil, nil
+}
@@ -0,0 +1,97 @@
+package genesis
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestParseBlockSelector(t *testing.T) {
+	hash := "0x" + "ab" + "00112233445566778899aabbccddeeff00112233445566778899aabbccddee"
+	for _, tc := range []struct {
+		selector string
+		number   *big.Int
+		hash     *common.Hash
+		err      bool
+	}{
+		{selector: ""},
+		{selector: "latest"},
+		{selector: "safe", number: big.NewInt(-4)},
+		{selector: "finalized", number: big.NewInt(-3)},
+		{selector: "12345", number: big.NewInt(12345)},
+		{selector: "0x10", number: big.NewInt(16)},
+		{selector: hash, hash: func() *common.Hash { h := common.HexToHash(hash); return &h }()},
+		{selector: "0x" + "zz" + hash[4:], err: true},
+		{selector: "-1", err: true},
+		{selector: "pending", err: true},
+	} {
+		number, h, err := ParseBlockSelector(tc.selector)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tc.selector)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.selector, err)
+			continue
+		}
+		if (number == nil) != (tc.number == nil) || (number != nil && number.Cmp(tc.number) != 0) {
+			t.Errorf("%q: expected number %v, got %v", tc.selector, tc.number, number)
+		}
+		if (h == nil) != (tc.hash == nil) || (h != nil && *h != *tc.hash) {
+			t.Errorf("%q: expected hash %v, got %v", tc.selector, tc.hash, h)
+		}
+	}
+}
+
+func TestLoadEth1BlockShadowForkTag(t *testing.T) {
+	block := (&core.Genesis{
+		Config:     &params.ChainConfig{ChainID: big.NewInt(1), LondonBlock: big.NewInt(0)},
+		Number:     42,
+		GasLimit:   30_000_000,
+		Difficulty: big.NewInt(0),
+	}).ToBlock()
+	var requested []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []any           `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_getBlockByNumber" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		requested = append(requested, req.Params[0])
+		var result map[string]any
+		headerData, _ := json.Marshal(block.Header())
+		_ = json.Unmarshal(headerData, &result)
+		result["transactions"] = []any{}
+		result["uncles"] = []any{}
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	defer srv.Close()
+
+	eth1Block, _, err := LoadEth1Block(context.Background(), "", srv.URL, "finalized", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eth1Block.Hash() != block.Hash() {
+		t.Fatalf("unexpected block %s", eth1Block.Hash())
+	}
+	if len(requested) != 1 || requested[0] != "finalized" {
+		t.Fatalf("expected a single request for the finalized block, got %v", requested)
+	}
+
+	if _, _, err := LoadEth1Block(context.Background(), "", "", "finalized", nil); err == nil {
+		t.Fatal("expected error when selecting a shadow-fork block without RPC")
+	}
+}
@@ -0,0 +1,310 @@
+package genesis
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/eth2-testnet-genesis/fulu"
+)
+
+// Fork describes everything that differs between forks when creating a genesis state.
+// Adding support for a new fork only requires a new entry in Forks.
+type Fork struct {
+	Name string
+	// Version returns the fork version of the fork.
+	// The previous fork version is taken from the fork before it in Forks.
+	Version func(spec *common.Spec) common.Version
+	// Epoch returns the epoch the fork is scheduled at in the consensus-layer config.
+	Epoch func(spec *common.Spec) common.Epoch
+	// Eth1ForkName is the name of the matching timestamp-based execution-layer fork, if any.
+	Eth1ForkName string
+	// Eth1ForkTime returns the activation time of the matching execution-layer fork, nil if not scheduled.
+	Eth1ForkTime func(cfg *params.ChainConfig) *uint64
+	// SetEth1ForkTime sets the activation time of the matching execution-layer fork, nil to not schedule it.
+	SetEth1ForkTime func(cfg *params.ChainConfig, t *uint64)
+	// MaxBlobsPerBlock optionally returns the blob limit of the fork, to check against Eth1BlobConfig.
+	MaxBlobsPerBlock func(spec *common.Spec) uint64
+	// Eth1BlobConfig returns the execution-layer blob config of the fork, nil if not configured.
+	Eth1BlobConfig func(cfg *params.ChainConfig) *params.BlobConfig
+	// NewState creates an empty beacon state of the fork.
+	NewState func(spec *common.Spec) common.BeaconState
+	// DecodeState decodes an SSZ beacon state of the fork.
+	DecodeState func(spec *common.Spec, dr *codec.DecodingReader) (common.BeaconState, error)
+	// BlockBodyType is the type of the beacon block body, used for the empty genesis block body.
+	BlockBodyType func(spec *common.Spec) *view.ContainerTypeDef
+	// CheckEth1Genesis optionally verifies that the execution-layer genesis config is compatible with the fork.
+	CheckEth1Genesis func(eth1Genesis *core.Genesis) error
+	// SetPayloadHeader builds the execution payload header from the execution-layer block, and sets it in the state.
+	// Nil for forks before the merge.
+	SetPayloadHeader func(spec *common.Spec, state common.BeaconState, eth1Block *types.Block, prevRandaoMix common.Bytes32) error
+	// PostSetup optionally initializes fork-specific state fields, after the validators have been added.
+	PostSetup func(spec *common.Spec, state common.BeaconState) error
+}
+
+var Forks = []*Fork{
+	{
+		Name:     "phase0",
+		Version:  func(spec *common.Spec) common.Version { return spec.GENESIS_FORK_VERSION },
+		Epoch:    func(spec *common.Spec) common.Epoch { return common.GENESIS_EPOCH },
+		NewState: func(spec *common.Spec) common.BeaconState { return phase0.NewBeaconStateView(spec) },
+		DecodeState: func(spec *common.Spec, dr *codec.DecodingReader) (common.BeaconState, error) {
+			return phase0.AsBeaconStateView(phase0.BeaconStateType(spec).Deserialize(dr))
+		},
+		BlockBodyType: phase0.BeaconBlockBodyType,
+	},
+	{
+		Name:     "altair",
+		Version:  func(spec *common.Spec) common.Version { return spec.ALTAIR_FORK_VERSION },
+		Epoch:    func(spec *common.Spec) common.Epoch { return spec.ALTAIR_FORK_EPOCH },
+		NewState: func(spec *common.Spec) common.BeaconState { return altair.NewBeaconStateView(spec) },
+		DecodeState: func(spec *common.Spec, dr *codec.DecodingReader) (common.BeaconState, error) {
+			return altair.AsBeaconStateView(altair.BeaconStateType(spec).Deserialize(dr))
+		},
+		BlockBodyType: altair.BeaconBlockBodyType,
+	},
+	{
+		Name:     "bellatrix",
+		Version:  func(spec *common.Spec) common.Version { return spec.BELLATRIX_FORK_VERSION },
+		Epoch:    func(spec *common.Spec) common.Epoch { return spec.BELLATRIX_FORK_EPOCH },
+		NewState: func(spec *common.Spec) common.BeaconState { return bellatrix.NewBeaconStateView(spec) },
+		DecodeState: func(spec *common.Spec, dr *codec.DecodingReader) (common.BeaconState, error) {
+			return bellatrix.AsBeaconStateView(bellatrix.BeaconStateType(spec).Deserialize(dr))
+		},
+		BlockBodyType: bellatrix.BeaconBlockBodyType,
+		SetPayloadHeader: func(spec *common.Spec, state common.BeaconState, eth1Block *types.Block, prevRandaoMix common.Bytes32) error {
+			h, err := BellatrixPayloadHeader(spec, eth1Block, prevRandaoMix)
+			if err != nil {
+				return err
+			}
+			return state.(*bellatrix.BeaconStateView).SetLatestExecutionPayloadHeader(h)
+		},
+	},
+	{
+		Name:            "capella",
+		Version:         func(spec *common.Spec) common.Version { return spec.CAPELLA_FORK_VERSION },
+		Epoch:           func(spec *common.Spec) common.Epoch { return spec.CAPELLA_FORK_EPOCH },
+		Eth1ForkName:    "shanghai",
+		Eth1ForkTime:    func(cfg *params.ChainConfig) *uint64 { return cfg.ShanghaiTime },
+		SetEth1ForkTime: func(cfg *params.ChainConfig, t *uint64) { cfg.ShanghaiTime = t },
+		NewState:        func(spec *common.Spec) common.BeaconState { return capella.NewBeaconStateView(spec) },
+		DecodeState: func(spec *common.Spec, dr *codec.DecodingReader) (common.BeaconState, error) {
+			return capella.AsBeaconStateView(capella.BeaconStateType(spec).Deserialize(dr))
+		},
+		BlockBodyType: capella.BeaconBlockBodyType,
+		SetPayloadHeader: func(spec *common.Spec, state common.BeaconState, eth1Block *types.Block, prevRandaoMix common.Bytes32) error {
+			h, err := CapellaPayloadHeader(spec, eth1Block, prevRandaoMix)
+			if err != nil {
+				return err
+			}
+			return state.(*capella.BeaconStateView).SetLatestExecutionPayloadHeader(h)
+		},
+	},
+	{
+		Name:             "deneb",
+		Version:          func(spec *common.Spec) common.Version { return spec.DENEB_FORK_VERSION },
+		Epoch:            func(spec *common.Spec) common.Epoch { return spec.DENEB_FORK_EPOCH },
+		Eth1ForkName:     "cancun",
+		Eth1ForkTime:     func(cfg *params.ChainConfig) *uint64 { return cfg.CancunTime },
+		SetEth1ForkTime:  func(cfg *params.ChainConfig, t *uint64) { cfg.CancunTime = t },
+		MaxBlobsPerBlock: func(spec *common.Spec) uint64 { return uint64(spec.MAX_BLOBS_PER_BLOCK) },
+		Eth1BlobConfig: func(cfg *params.ChainConfig) *params.BlobConfig {
+			if cfg.BlobScheduleConfig == nil {
+				return nil
+			}
+			return cfg.BlobScheduleConfig.Cancun
+		},
+		NewState: func(spec *common.Spec) common.BeaconState { return deneb.NewBeaconStateView(spec) },
+		DecodeState: func(spec *common.Spec, dr *codec.DecodingReader) (common.BeaconState, error) {
+			return deneb.AsBeaconStateView(deneb.BeaconStateType(spec).Deserialize(dr))
+		},
+		BlockBodyType: deneb.BeaconBlockBodyType,
+		SetPayloadHeader: func(spec *common.Spec, state common.BeaconState, eth1Block *types.Block, prevRandaoMix common.Bytes32) error {
+			h, err := DenebPayloadHeader(spec, eth1Block, prevRandaoMix)
+			if err != nil {
+				return err
+			}
+			return state.(*deneb.BeaconStateView).SetLatestExecutionPayloadHeader(h)
+		},
+	},
+	{
+		Name:             "electra",
+		Version:          func(spec *common.Spec) common.Version { return spec.ELECTRA_FORK_VERSION },
+		Epoch:            func(spec *common.Spec) common.Epoch { return spec.ELECTRA_FORK_EPOCH },
+		Eth1ForkName:     "prague",
+		Eth1ForkTime:     func(cfg *params.ChainConfig) *uint64 { return cfg.PragueTime },
+		SetEth1ForkTime:  func(cfg *params.ChainConfig, t *uint64) { cfg.PragueTime = t },
+		MaxBlobsPerBlock: func(spec *common.Spec) uint64 { return uint64(spec.MAX_BLOBS_PER_BLOCK_ELECTRA) },
+		Eth1BlobConfig: func(cfg *params.ChainConfig) *params.BlobConfig {
+			if cfg.BlobScheduleConfig == nil {
+				return nil
+			}
+			return cfg.BlobScheduleConfig.Prague
+		},
+		NewState: func(spec *common.Spec) common.BeaconState { return electra.NewBeaconStateView(spec) },
+		DecodeState: func(spec *common.Spec, dr *codec.DecodingReader) (common.BeaconState, error) {
+			return electra.AsBeaconStateView(electra.BeaconStateType(spec).Deserialize(dr))
+		},
+		BlockBodyType: electra.BeaconBlockBodyType,
+		SetPayloadHeader: func(spec *common.Spec, state common.BeaconState, eth1Block *types.Block, prevRandaoMix common.Bytes32) error {
+			h, err := ElectraPayloadHeader(spec, eth1Block, prevRandaoMix)
+			if err != nil {
+				return err
+			}
+			return state.(*electra.BeaconStateView).SetLatestExecutionPayloadHeader(h)
+		},
+		PostSetup: func(spec *common.Spec, state common.BeaconState) error {
+			return setupElectraState(spec, state.(*electra.BeaconStateView))
+		},
+	},
+	{
+		Name:            "fulu",
+		Version:         func(spec *common.Spec) common.Version { return spec.FULU_FORK_VERSION },
+		Epoch:           func(spec *common.Spec) common.Epoch { return spec.FULU_FORK_EPOCH },
+		Eth1ForkName:    "osaka",
+		Eth1ForkTime:    func(cfg *params.ChainConfig) *uint64 { return cfg.OsakaTime },
+		SetEth1ForkTime: func(cfg *params.ChainConfig, t *uint64) { cfg.OsakaTime = t },
+		// No MaxBlobsPerBlock: the blob limit is in the BLOB_SCHEDULE, and there is no Osaka blob config, see UncheckedConfigs.
+		NewState: func(spec *common.Spec) common.BeaconState { return fulu.NewBeaconStateView(spec) },
+		DecodeState: func(spec *common.Spec, dr *codec.DecodingReader) (common.BeaconState, error) {
+			return fulu.AsBeaconStateView(fulu.BeaconStateType(spec).Deserialize(dr))
+		},
+		// The block body is unchanged in Fulu
+		BlockBodyType: electra.BeaconBlockBodyType,
+		CheckEth1Genesis: func(eth1Genesis *core.Genesis) error {
+			// PeerDAS comes with the Osaka execution-layer upgrade, which must be active at genesis.
+			if eth1Genesis.Config == nil || !eth1Genesis.Config.IsOsaka(new(big.Int).SetUint64(eth1Genesis.Number), eth1Genesis.Timestamp) {
+				return errors.New("execution-layer genesis config does not have Osaka enabled at genesis")
+			}
+			return nil
+		},
+		SetPayloadHeader: func(spec *common.Spec, state common.BeaconState, eth1Block *types.Block, prevRandaoMix common.Bytes32) error {
+			h, err := ElectraPayloadHeader(spec, eth1Block, prevRandaoMix)
+			if err != nil {
+				return err
+			}
+			return state.(*fulu.BeaconStateView).SetLatestExecutionPayloadHeader(h)
+		},
+		PostSetup: func(spec *common.Spec, state common.BeaconState) error {
+			st := state.(*fulu.BeaconStateView)
+			if err := setupElectraState(spec, st.BeaconStateView); err != nil {
+				return err
+			}
+			// New in Fulu: the proposers of the current and next epoch are tracked in the state.
+			if err := initializeProposerLookahead(spec, st); err != nil {
+				return fmt.Errorf("failed to initialize proposer lookahead: %w", err)
+			}
+			return nil
+		},
+	},
+}
+
+func ForkNames() []string {
+	names := make([]string, len(Forks))
+	for i, f := range Forks {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func ForkByName(name string) (*Fork, error) {
+	if name == "merge" {
+		name = "bellatrix"
+	}
+	for _, f := range Forks {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown fork %q, expected one of: %s", name, strings.Join(ForkNames(), ", "))
+}
+
+// Versions returns the previous and current fork version, as put in the genesis state.
+func (f *Fork) Versions(spec *common.Spec) (previous common.Version, current common.Version) {
+	for i, other := range Forks {
+		if other == f {
+			if i == 0 {
+				return f.Version(spec), f.Version(spec)
+			}
+			return Forks[i-1].Version(spec), f.Version(spec)
+		}
+	}
+	panic(fmt.Errorf("fork %q is not registered", f.Name))
+}
+
+// AtLeast returns true if the fork is the given fork, or a later fork.
+func (f *Fork) AtLeast(name string) bool {
+	other, err := ForkByName(name)
+	if err != nil {
+		panic(err)
+	}
+	for _, fork := range Forks {
+		if fork == other {
+			return true
+		}
+		if fork == f {
+			return false
+		}
+	}
+	return false
+}
+
+func setupElectraState(spec *common.Spec, state *electra.BeaconStateView) error {
+	// To compute epochs: like the deneb-to-electra fork logic.
+	currentEpoch := common.Epoch(0)
+	earliestExitEpoch := spec.ComputeActivationExitEpoch(currentEpoch)
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	// New genesis validators do not have an exit epoch, but validators of a regenesis may be exiting already:
+	// like the fork upgrade, the exits continue after the latest exit epoch.
+	indicesBounded, err := common.LoadBoundedIndices(vals)
+	if err != nil {
+		return err
+	}
+	for _, v := range indicesBounded {
+		if v.Exit != common.FAR_FUTURE_EPOCH && v.Exit > earliestExitEpoch {
+			earliestExitEpoch = v.Exit
+		}
+	}
+	earliestExitEpoch += 1 // in the fork upgrade spec we add 1, so we do that here too...
+
+	earliestConsolidationEpoch := spec.ComputeActivationExitEpoch(currentEpoch)
+
+	// Like the deneb-to-electra fork logic, the churn is based on the total active balance.
+	totalActiveBalance, err := TotalActiveBalance(spec, vals, currentEpoch)
+	if err != nil {
+		return err
+	}
+	exitBalanceToConsume := ActivationExitChurnLimit(spec, totalActiveBalance)
+	consolidationBalanceToConsume := ConsolidationChurnLimit(spec, totalActiveBalance)
+	if err := state.SetEarliestExitEpoch(earliestExitEpoch); err != nil {
+		return err
+	}
+	if err := state.SetEarliestConsolidationEpoch(earliestConsolidationEpoch); err != nil {
+		return err
+	}
+	if err := state.SetExitBalanceToConsume(exitBalanceToConsume); err != nil {
+		return err
+	}
+	if err := state.SetConsolidationBalanceToConsume(consolidationBalanceToConsume); err != nil {
+		return err
+	}
+	return nil
+}
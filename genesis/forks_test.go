@@ -0,0 +1,12 @@
+package genesis
+
+import "testing"
+
+func TestGenesisForkByName(t *testing.T) {
+	if f, err := ForkByName("merge"); err != nil || f.Name != "bellatrix" {
+		t.Fatalf("expected merge alias for bellatrix, got %v, %v", f, err)
+	}
+	if _, err := ForkByName("unknown"); err == nil {
+		t.Fatal("expected error for unknown fork")
+	}
+}
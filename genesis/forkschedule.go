@@ -0,0 +1,193 @@
+package genesis
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/view"
+)
+
+// ScheduledFork is a fork of the fork schedule, like in the /eth/v1/config/fork_schedule Beacon API.
+type ScheduledFork struct {
+	Name            string         `json:"-" yaml:"name"`
+	PreviousVersion common.Version `json:"previous_version" yaml:"previous_version"`
+	CurrentVersion  common.Version `json:"current_version" yaml:"current_version"`
+	Epoch           common.Epoch   `json:"epoch" yaml:"epoch"`
+}
+
+// ForkSchedule returns the forks that are scheduled in the spec, in order, starting with phase0 at genesis.
+// Forks at FAR_FUTURE_EPOCH are not scheduled.
+func ForkSchedule(spec *common.Spec) []ScheduledFork {
+	var schedule []ScheduledFork
+	for _, f := range Forks {
+		epoch := f.Epoch(spec)
+		if epoch == common.FAR_FUTURE_EPOCH {
+			break
+		}
+		previous, current := f.Versions(spec)
+		schedule = append(schedule, ScheduledFork{
+			Name:            f.Name,
+			PreviousVersion: previous,
+			CurrentVersion:  current,
+			Epoch:           epoch,
+		})
+	}
+	return schedule
+}
+
+// BlobParameters is an entry of the BLOB_SCHEDULE of the config: the blob limit from the epoch on.
+// Entries after the Fulu fork are blob parameter only (BPO) forks, which change the fork digest.
+type BlobParameters struct {
+	Epoch            common.Epoch    `yaml:"EPOCH" json:"EPOCH"`
+	MaxBlobsPerBlock view.Uint64View `yaml:"MAX_BLOBS_PER_BLOCK" json:"MAX_BLOBS_PER_BLOCK"`
+}
+
+// ParseBlobSchedule parses the BLOB_SCHEDULE of a config YAML. The spec type of zrnt does not have it.
+// The schedule is empty if the config has none.
+func ParseBlobSchedule(configYAML []byte) ([]BlobParameters, error) {
+	// Uint64View decodes quoted and unquoted numbers, the epoch type only unquoted ones.
+	var config struct {
+		BlobSchedule []struct {
+			Epoch            view.Uint64View `yaml:"EPOCH"`
+			MaxBlobsPerBlock view.Uint64View `yaml:"MAX_BLOBS_PER_BLOCK"`
+		} `yaml:"BLOB_SCHEDULE"`
+	}
+	if err := yaml.Unmarshal(configYAML, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode BLOB_SCHEDULE of config: %w", err)
+	}
+	var schedule []BlobParameters
+	for _, entry := range config.BlobSchedule {
+		schedule = append(schedule, BlobParameters{Epoch: common.Epoch(entry.Epoch), MaxBlobsPerBlock: entry.MaxBlobsPerBlock})
+	}
+	return schedule, nil
+}
+
+// blobParameters returns the blob parameters at the epoch, like get_blob_parameters of Fulu.
+func blobParameters(spec *common.Spec, blobSchedule []BlobParameters, epoch common.Epoch) BlobParameters {
+	params := BlobParameters{Epoch: spec.ELECTRA_FORK_EPOCH, MaxBlobsPerBlock: spec.MAX_BLOBS_PER_BLOCK_ELECTRA}
+	found := false
+	for _, entry := range blobSchedule {
+		if entry.Epoch <= epoch && (!found || entry.Epoch >= params.Epoch) {
+			params = entry
+			found = true
+		}
+	}
+	return params
+}
+
+// ComputeForkDigest computes the fork digest at the epoch, like compute_fork_digest of Fulu:
+// from Fulu on, the fork data root is mixed with the blob parameters of the epoch, before it is truncated.
+// The versions of the forks before Fulu keep their regular digest, also at the Fulu epoch.
+// This is the only fork digest computation, for the genesis state details and the fork schedule alike.
+func ComputeForkDigest(spec *common.Spec, blobSchedule []BlobParameters, version common.Version, genesisValidatorsRoot common.Root, epoch common.Epoch) common.ForkDigest {
+	root := common.ComputeForkDataRoot(version, genesisValidatorsRoot)
+	if fork, err := forkByVersion(spec, version); epoch >= spec.FULU_FORK_EPOCH && (err != nil || fork.AtLeast("fulu")) {
+		params := blobParameters(spec, blobSchedule, epoch)
+		var data [16]byte
+		binary.LittleEndian.PutUint64(data[:8], uint64(params.Epoch))
+		binary.LittleEndian.PutUint64(data[8:], uint64(params.MaxBlobsPerBlock))
+		mix := sha256.Sum256(data[:])
+		for i := range root {
+			root[i] ^= mix[i]
+		}
+	}
+	var digest common.ForkDigest
+	copy(digest[:], root[:4])
+	return digest
+}
+
+// ForkDigestEntry is a fork of the network, or a BPO fork, with the fork digest and the ENR fork id from its epoch on.
+type ForkDigestEntry struct {
+	// Name is the fork name, or bpo1, bpo2, etc. for the blob parameter only forks.
+	Name    string         `json:"name" yaml:"name"`
+	Version common.Version `json:"version" yaml:"version"`
+	Epoch   common.Epoch   `json:"epoch" yaml:"epoch"`
+	// MaxBlobsPerBlock is the blob limit of the Fulu and BPO forks, that is part of the digest. Zero for the forks before Fulu.
+	MaxBlobsPerBlock uint64            `json:"max_blobs_per_block,omitempty" yaml:"max_blobs_per_block,omitempty"`
+	Digest           common.ForkDigest `json:"digest" yaml:"digest"`
+	// NextForkVersion and NextForkEpoch are the next fork after this one, excluding BPO forks,
+	// or the version of this fork and FAR_FUTURE_EPOCH if there is none.
+	NextForkVersion common.Version `json:"next_fork_version" yaml:"next_fork_version"`
+	NextForkEpoch   common.Epoch   `json:"next_fork_epoch" yaml:"next_fork_epoch"`
+	// NextForkDigest is the digest of the next fork, including BPO forks, zero if there is none. The nfd ENR field of Fulu.
+	NextForkDigest common.ForkDigest `json:"next_fork_digest" yaml:"next_fork_digest"`
+	// ENRForkID is the SSZ of the ENRForkID: the eth2 ENR field.
+	ENRForkID hexutil.Bytes `json:"enr_fork_id" yaml:"enr_fork_id"`
+}
+
+// ForkDigestSchedule returns the forks of ForkSchedule, and the BPO forks of the blob schedule after Fulu,
+// in order of epoch, with their digests of the network of the genesis validators root.
+// Forks at the same epoch are all listed, the last one is the fork of that epoch.
+// Only Fulu and the BPO forks have digests that are mixed with the blob parameters.
+func ForkDigestSchedule(spec *common.Spec, blobSchedule []BlobParameters, genesisValidatorsRoot common.Root) []ForkDigestEntry {
+	var entries []ForkDigestEntry
+	forks := ForkSchedule(spec)
+	// isFork is true for the entries of actual forks, false for the BPO forks.
+	var isFork []bool
+	for _, f := range forks {
+		entries = append(entries, ForkDigestEntry{Name: f.Name, Version: f.CurrentVersion, Epoch: f.Epoch})
+		isFork = append(isFork, true)
+	}
+	// The BPO forks, in order of epoch. A blob schedule entry at the epoch of a fork only changes the digest of the fork.
+	bpo := slices.Clone(blobSchedule)
+	slices.SortStableFunc(bpo, func(a, b BlobParameters) int { return cmp.Compare(a.Epoch, b.Epoch) })
+	bpoCount := 0
+	for _, params := range bpo {
+		if params.Epoch <= spec.FULU_FORK_EPOCH || spec.FULU_FORK_EPOCH == common.FAR_FUTURE_EPOCH || params.Epoch == common.FAR_FUTURE_EPOCH {
+			continue
+		}
+		i := slices.IndexFunc(entries, func(e ForkDigestEntry) bool { return e.Epoch >= params.Epoch })
+		if i >= 0 && entries[i].Epoch == params.Epoch {
+			continue
+		}
+		if i < 0 {
+			i = len(entries)
+		}
+		bpoCount++
+		// The fork version stays the same as the fork before it.
+		entry := ForkDigestEntry{Name: fmt.Sprintf("bpo%d", bpoCount), Version: entries[i-1].Version, Epoch: params.Epoch}
+		entries = slices.Insert(entries, i, entry)
+		isFork = slices.Insert(isFork, i, false)
+	}
+
+	fulu := slices.IndexFunc(entries, func(e ForkDigestEntry) bool { return e.Name == "fulu" })
+	for i := range entries {
+		e := &entries[i]
+		e.Digest = ComputeForkDigest(spec, blobSchedule, e.Version, genesisValidatorsRoot, e.Epoch)
+		if fulu >= 0 && i >= fulu {
+			e.MaxBlobsPerBlock = uint64(blobParameters(spec, blobSchedule, e.Epoch).MaxBlobsPerBlock)
+		}
+	}
+	for i := range entries {
+		e := &entries[i]
+		e.NextForkVersion, e.NextForkEpoch = e.Version, common.FAR_FUTURE_EPOCH
+		nextDigestFound := false
+		for j := i + 1; j < len(entries); j++ {
+			next := &entries[j]
+			if next.Epoch <= e.Epoch {
+				continue
+			}
+			if !nextDigestFound {
+				e.NextForkDigest = next.Digest
+				nextDigestFound = true
+			}
+			if isFork[j] {
+				e.NextForkVersion, e.NextForkEpoch = next.Version, next.Epoch
+				break
+			}
+		}
+		// ENRForkID: fork_digest, next_fork_version, next_fork_epoch
+		e.ENRForkID = make(hexutil.Bytes, 0, 16)
+		e.ENRForkID = append(e.ENRForkID, e.Digest[:]...)
+		e.ENRForkID = append(e.ENRForkID, e.NextForkVersion[:]...)
+		e.ENRForkID = binary.LittleEndian.AppendUint64(e.ENRForkID, uint64(e.NextForkEpoch))
+	}
+	return entries
+}
@@ -0,0 +1,157 @@
+package genesis
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestParseBlobSchedule(t *testing.T) {
+	schedule, err := ParseBlobSchedule([]byte(`
+PRESET_BASE: minimal
+FULU_FORK_EPOCH: 20
+BLOB_SCHEDULE:
+  - EPOCH: 20
+    MAX_BLOBS_PER_BLOCK: 12
+  - EPOCH: "30"
+    MAX_BLOBS_PER_BLOCK: "15"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []BlobParameters{{Epoch: 20, MaxBlobsPerBlock: 12}, {Epoch: 30, MaxBlobsPerBlock: 15}}
+	if len(schedule) != len(expected) || schedule[0] != expected[0] || schedule[1] != expected[1] {
+		t.Fatalf("unexpected blob schedule: %v", schedule)
+	}
+	if schedule, err := ParseBlobSchedule([]byte("PRESET_BASE: minimal\n")); err != nil || len(schedule) != 0 {
+		t.Fatalf("expected empty blob schedule, got %v, %v", schedule, err)
+	}
+	if _, err := ParseBlobSchedule([]byte("BLOB_SCHEDULE: 1\n")); err == nil {
+		t.Fatal("expected error for invalid blob schedule")
+	}
+}
+
+func TestForkDigestSchedule(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ELECTRA_FORK_EPOCH = 10
+	spec.FULU_FORK_EPOCH = 20
+	spec.MAX_BLOBS_PER_BLOCK_ELECTRA = 9
+	blobSchedule := []BlobParameters{
+		{Epoch: 40, MaxBlobsPerBlock: 21},
+		{Epoch: 30, MaxBlobsPerBlock: 15},
+		// before and at Fulu: not a BPO fork
+		{Epoch: 10, MaxBlobsPerBlock: 9},
+		{Epoch: 20, MaxBlobsPerBlock: 12},
+	}
+	gvr := common.Root{0xaa, 0xbb}
+	schedule := ForkDigestSchedule(&spec, blobSchedule, gvr)
+
+	names := []string{"phase0", "altair", "bellatrix", "capella", "deneb", "electra", "fulu", "bpo1", "bpo2"}
+	if len(schedule) != len(names) {
+		t.Fatalf("expected %d forks, got %d: %v", len(names), len(schedule), schedule)
+	}
+	for i, name := range names {
+		if schedule[i].Name != name {
+			t.Fatalf("expected fork %d to be %s, got %s", i, name, schedule[i].Name)
+		}
+	}
+
+	// before Fulu, the digest is the regular fork digest
+	deneb := schedule[4]
+	if deneb.Digest != common.ComputeForkDigest(spec.DENEB_FORK_VERSION, gvr) {
+		t.Fatalf("unexpected deneb digest %s", deneb.Digest)
+	}
+	if deneb.NextForkVersion != spec.ELECTRA_FORK_VERSION || deneb.NextForkEpoch != 10 {
+		t.Fatalf("unexpected deneb next fork %s at %d", deneb.NextForkVersion, deneb.NextForkEpoch)
+	}
+	electra := schedule[5]
+	if electra.MaxBlobsPerBlock != 0 || electra.NextForkDigest != schedule[6].Digest {
+		t.Fatalf("unexpected electra entry: %v", electra)
+	}
+
+	// from Fulu on, the digest is mixed with the blob parameters
+	bpoDigest := func(version common.Version, epoch common.Epoch, maxBlobs uint64) common.ForkDigest {
+		root := common.ComputeForkDataRoot(version, gvr)
+		var data [16]byte
+		binary.LittleEndian.PutUint64(data[:8], uint64(epoch))
+		binary.LittleEndian.PutUint64(data[8:], maxBlobs)
+		mix := sha256.Sum256(data[:])
+		var digest common.ForkDigest
+		for i := range digest {
+			digest[i] = root[i] ^ mix[i]
+		}
+		return digest
+	}
+	for i, expected := range []struct {
+		epoch    common.Epoch
+		maxBlobs uint64
+	}{{20, 12}, {30, 15}, {40, 21}} {
+		e := schedule[6+i]
+		if e.Version != spec.FULU_FORK_VERSION || e.Epoch != expected.epoch || e.MaxBlobsPerBlock != expected.maxBlobs {
+			t.Fatalf("unexpected %s entry: %v", e.Name, e)
+		}
+		if d := bpoDigest(spec.FULU_FORK_VERSION, expected.epoch, expected.maxBlobs); e.Digest != d {
+			t.Fatalf("expected %s digest %s, got %s", e.Name, d, e.Digest)
+		}
+		// BPO forks are not in the next fork version and epoch of the eth2 ENR field, only in the next fork digest
+		if e.NextForkVersion != spec.FULU_FORK_VERSION || e.NextForkEpoch != common.FAR_FUTURE_EPOCH {
+			t.Fatalf("unexpected %s next fork %s at %d", e.Name, e.NextForkVersion, e.NextForkEpoch)
+		}
+	}
+	if schedule[6].NextForkDigest != schedule[7].Digest || schedule[7].NextForkDigest != schedule[8].Digest {
+		t.Fatal("expected the next fork digest of the BPO forks")
+	}
+	if schedule[8].NextForkDigest != (common.ForkDigest{}) {
+		t.Fatalf("expected no next fork digest of the last fork, got %s", schedule[8].NextForkDigest)
+	}
+
+	// eth2 ENR field: fork_digest ++ next_fork_version ++ next_fork_epoch
+	enr := deneb.ENRForkID
+	if len(enr) != 16 || common.ForkDigest(enr[:4]) != deneb.Digest ||
+		common.Version(enr[4:8]) != spec.ELECTRA_FORK_VERSION || binary.LittleEndian.Uint64(enr[8:]) != 10 {
+		t.Fatalf("unexpected deneb ENR fork id %s", enr)
+	}
+
+	// without a blob schedule, Fulu uses the Electra blob parameters
+	schedule = ForkDigestSchedule(&spec, nil, gvr)
+	if len(schedule) != 7 {
+		t.Fatalf("expected no BPO forks, got %v", schedule)
+	}
+	if d := bpoDigest(spec.FULU_FORK_VERSION, 10, 9); schedule[6].Digest != d || schedule[6].MaxBlobsPerBlock != 9 {
+		t.Fatalf("expected fulu digest %s with the electra blob parameters, got %v", d, schedule[6])
+	}
+}
+
+func TestForkDigestScheduleFuluGenesis(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ELECTRA_FORK_EPOCH = 0
+	spec.FULU_FORK_EPOCH = 0
+	gvr := common.Root{0xcc}
+	schedule := ForkDigestSchedule(&spec, []BlobParameters{{Epoch: 0, MaxBlobsPerBlock: 9}, {Epoch: 5, MaxBlobsPerBlock: 15}}, gvr)
+	if len(schedule) != 8 || schedule[7].Name != "bpo1" {
+		t.Fatalf("unexpected schedule: %v", schedule)
+	}
+	// the forks before Fulu at the Fulu epoch keep their regular digest
+	electra := schedule[5]
+	if electra.Digest != common.ComputeForkDigest(spec.ELECTRA_FORK_VERSION, gvr) || electra.MaxBlobsPerBlock != 0 {
+		t.Fatalf("unexpected electra entry: %v", electra)
+	}
+	fulu := schedule[6]
+	if fulu.Digest == common.ComputeForkDigest(spec.FULU_FORK_VERSION, gvr) || fulu.MaxBlobsPerBlock != 9 {
+		t.Fatalf("unexpected fulu entry: %v", fulu)
+	}
+	if fulu.NextForkDigest != schedule[7].Digest || fulu.NextForkEpoch != common.FAR_FUTURE_EPOCH {
+		t.Fatalf("unexpected fulu next fork: %v", fulu)
+	}
+}
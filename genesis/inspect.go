@@ -0,0 +1,158 @@
+package genesis
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// The fork is at the same place in the state of every fork:
+// after the genesis time (8 bytes), the genesis validators root (32 bytes) and the slot (8 bytes).
+const stateForkOffset = 8 + 32 + 8
+
+// DetectFork finds the fork of an SSZ-encoded beacon state, by matching fork.current_version against the spec.
+// If multiple forks share the same version, the latest fork is used.
+func DetectFork(spec *common.Spec, stateData []byte) (*Fork, error) {
+	if len(stateData) < stateForkOffset+8 {
+		return nil, fmt.Errorf("state is too short: %d bytes", len(stateData))
+	}
+	var currentVersion common.Version
+	copy(currentVersion[:], stateData[stateForkOffset+4:stateForkOffset+8])
+	return forkByVersion(spec, currentVersion)
+}
+
+// forkByVersion finds the latest fork with the version.
+func forkByVersion(spec *common.Spec, version common.Version) (*Fork, error) {
+	for i := len(Forks) - 1; i >= 0; i-- {
+		if Forks[i].Version(spec) == version {
+			return Forks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("state fork version %s does not match any fork version of the spec", version)
+}
+
+// DecodeState decodes an SSZ-encoded beacon state of any fork.
+func DecodeState(spec *common.Spec, stateData []byte) (common.BeaconState, *Fork, error) {
+	fork, err := DetectFork(spec, stateData)
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := fork.DecodeState(spec, codec.NewDecodingReader(bytes.NewReader(stateData), uint64(len(stateData))))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s state: %w", fork.Name, err)
+	}
+	return state, fork, nil
+}
+
+// Details summarizes a genesis state, with the information clients and tooling need to join the network.
+type Details struct {
+	Fork                             string                   `json:"fork" yaml:"fork"`
+	GenesisTime                      common.Timestamp         `json:"genesis_time" yaml:"genesis_time"`
+	GenesisStateRoot                 common.Root              `json:"genesis_state_root" yaml:"genesis_state_root"`
+	GenesisLatestBlockHeader         common.BeaconBlockHeader `json:"genesis_latest_block_header" yaml:"genesis_latest_block_header"`
+	GenesisBlockRootNoStateRoot      common.Root              `json:"genesis_block_root_no_state_root" yaml:"genesis_block_root_no_state_root"`
+	GenesisBlockRootUpdatedStateRoot common.Root              `json:"genesis_block_root_updated_state_root" yaml:"genesis_block_root_updated_state_root"`
+	GenesisValidatorsRoot            common.Root              `json:"genesis_validators_root" yaml:"genesis_validators_root"`
+	GenesisValidatorsCount           uint64                   `json:"genesis_validators_count" yaml:"genesis_validators_count"`
+	GenesisActiveValidatorsCount     uint64                   `json:"genesis_active_validators_count" yaml:"genesis_active_validators_count"`
+	GenesisTotalActiveStakeGwei      common.Gwei              `json:"genesis_total_active_stake_gwei" yaml:"genesis_total_active_stake_gwei"`
+	GenesisTotalBalanceGwei          common.Gwei              `json:"genesis_total_balance_gwei" yaml:"genesis_total_balance_gwei"`
+	Eth1Data                         common.Eth1Data          `json:"eth1_data" yaml:"eth1_data"`
+	DepositIndex                     common.DepositIndex      `json:"deposit_index" yaml:"deposit_index"`
+	GenesisForkVersion               common.Version           `json:"genesis_fork_version" yaml:"genesis_fork_version"`
+	GenesisForkDigest                common.ForkDigest        `json:"genesis_fork_digest" yaml:"genesis_fork_digest"`
+	PreGenesisForkDigest             common.ForkDigest        `json:"pre_genesis_fork_digest" yaml:"pre_genesis_fork_digest"`
+}
+
+// Inspect computes the details of a genesis state.
+// The blob schedule is the BLOB_SCHEDULE of the config, that is part of the fork digest from Fulu on.
+func Inspect(spec *common.Spec, blobSchedule []BlobParameters, fork *Fork, state common.BeaconState) (*Details, error) {
+	hFn := tree.GetHashFn()
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	header, err := state.LatestBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+	if header.StateRoot != (common.Root{}) {
+		return nil, fmt.Errorf("expected empty state root in latest block header of genesis state, got %s", header.StateRoot)
+	}
+	stateRoot := state.HashTreeRoot(hFn)
+	updatedHeader := *header
+	updatedHeader.StateRoot = stateRoot
+
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	valCount, err := vals.ValidatorCount()
+	if err != nil {
+		return nil, err
+	}
+	validatorsRoot := vals.HashTreeRoot(hFn)
+
+	indicesBounded, err := common.LoadBoundedIndices(vals)
+	if err != nil {
+		return nil, err
+	}
+	active := common.ActiveIndices(indicesBounded, common.GENESIS_EPOCH)
+	activeStake, err := TotalActiveBalance(spec, vals, common.GENESIS_EPOCH)
+	if err != nil {
+		return nil, err
+	}
+
+	bals, err := state.Balances()
+	if err != nil {
+		return nil, err
+	}
+	var totalBalance common.Gwei
+	for i := uint64(0); i < valCount; i++ {
+		bal, err := bals.GetBalance(common.ValidatorIndex(i))
+		if err != nil {
+			return nil, err
+		}
+		totalBalance += bal
+	}
+
+	eth1Data, err := state.Eth1Data()
+	if err != nil {
+		return nil, err
+	}
+	depositIndex, err := state.Eth1DepositIndex()
+	if err != nil {
+		return nil, err
+	}
+	stateFork, err := state.Fork()
+	if err != nil {
+		return nil, err
+	}
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	epoch := spec.SlotToEpoch(slot)
+
+	return &Details{
+		Fork:                             fork.Name,
+		GenesisTime:                      genesisTime,
+		GenesisStateRoot:                 stateRoot,
+		GenesisLatestBlockHeader:         *header,
+		GenesisBlockRootNoStateRoot:      header.HashTreeRoot(hFn),
+		GenesisBlockRootUpdatedStateRoot: updatedHeader.HashTreeRoot(hFn),
+		GenesisValidatorsRoot:            validatorsRoot,
+		GenesisValidatorsCount:           valCount,
+		GenesisActiveValidatorsCount:     uint64(len(active)),
+		GenesisTotalActiveStakeGwei:      activeStake,
+		GenesisTotalBalanceGwei:          totalBalance,
+		Eth1Data:                         eth1Data,
+		DepositIndex:                     depositIndex,
+		GenesisForkVersion:               stateFork.CurrentVersion,
+		GenesisForkDigest:                ComputeForkDigest(spec, blobSchedule, stateFork.CurrentVersion, validatorsRoot, epoch),
+		PreGenesisForkDigest:             ComputeForkDigest(spec, blobSchedule, stateFork.CurrentVersion, common.Root{}, epoch),
+	}, nil
+}
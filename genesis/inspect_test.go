@@ -0,0 +1,81 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+)
+
+func TestDecodeAndInspect(t *testing.T) {
+	validators := testValidators(t, configs.Minimal, 64)
+	// The last validator is not active at genesis
+	validators[63].Balance = configs.Minimal.MAX_EFFECTIVE_BALANCE / 2
+	// Fulu at genesis, the digest of which is mixed with the blob parameters
+	fuluSpec := *configs.Minimal
+	fuluSpec.ALTAIR_FORK_EPOCH = 0
+	fuluSpec.BELLATRIX_FORK_EPOCH = 0
+	fuluSpec.CAPELLA_FORK_EPOCH = 0
+	fuluSpec.DENEB_FORK_EPOCH = 0
+	fuluSpec.ELECTRA_FORK_EPOCH = 0
+	fuluSpec.FULU_FORK_EPOCH = 0
+	blobSchedule := []BlobParameters{{Epoch: 0, MaxBlobsPerBlock: 12}}
+	for _, forkName := range []string{"phase0", "altair", "fulu"} {
+		t.Run(forkName, func(t *testing.T) {
+			spec := configs.Minimal
+			if forkName == "fulu" {
+				spec = &fuluSpec
+			}
+			res, err := Build(context.Background(), &Options{
+				Spec:       spec,
+				Fork:       forkName,
+				Validators: validators,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := res.State.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+				t.Fatal(err)
+			}
+			state, fork, err := DecodeState(spec, buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fork.Name != forkName {
+				t.Fatalf("detected fork %s, expected %s", fork.Name, forkName)
+			}
+			details, err := Inspect(spec, blobSchedule, fork, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if details.GenesisValidatorsRoot != res.GenesisValidatorsRoot {
+				t.Fatalf("validators root mismatch: %s <> %s", details.GenesisValidatorsRoot, res.GenesisValidatorsRoot)
+			}
+			if details.GenesisValidatorsCount != 64 || details.GenesisActiveValidatorsCount != 63 {
+				t.Fatalf("unexpected validator counts: %d, %d", details.GenesisValidatorsCount, details.GenesisActiveValidatorsCount)
+			}
+			if details.GenesisTotalActiveStakeGwei != 63*spec.MAX_EFFECTIVE_BALANCE {
+				t.Fatalf("unexpected active stake: %d", details.GenesisTotalActiveStakeGwei)
+			}
+			_, version := fork.Versions(spec)
+			expected := common.ComputeForkDigest(version, res.GenesisValidatorsRoot)
+			if forkName == "fulu" {
+				// the fork data root, mixed with the hash of the blob parameters epoch and limit
+				root := common.ComputeForkDataRoot(version, res.GenesisValidatorsRoot)
+				mix := sha256.Sum256(binary.LittleEndian.AppendUint64(make([]byte, 8), 12))
+				for i := range expected {
+					expected[i] = root[i] ^ mix[i]
+				}
+			}
+			if details.GenesisForkDigest != expected {
+				t.Fatalf("unexpected fork digest: %s, expected %s", details.GenesisForkDigest, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package genesis
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"path/filepath"
+
+	"golang.org/x/sync/errgroup"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// blsCurveOrder is the order r of the BLS12-381 curve.
+var blsCurveOrder, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// InteropSecretKey is the insecure interop secret key of a validator index, as used by the consensus-spec tests
+// and client interop modes: int.from_bytes(sha256(index.to_bytes(32, 'little')), 'little') % curve_order.
+func InteropSecretKey(index uint64) (*blsu.SecretKey, error) {
+	var input [32]byte
+	binary.LittleEndian.PutUint64(input[:8], index)
+	h := sha256.Sum256(input[:])
+	// little-endian to big-endian
+	for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+		h[i], h[j] = h[j], h[i]
+	}
+	k := new(big.Int).SetBytes(h[:])
+	k.Mod(k, blsCurveOrder)
+	var skBytes [32]byte
+	k.FillBytes(skBytes[:])
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&skBytes); err != nil {
+		return nil, fmt.Errorf("invalid interop secret key %d: %w", index, err)
+	}
+	return &sk, nil
+}
+
+// GenerateInteropValidators creates count validators with the interop keys 0, 1, 2, etc., and writes their pubkeys
+// to the "interop" tranche file. The validators have BLS withdrawal credentials of their own pubkey,
+// like in the consensus-spec tests, or 0x01 credentials if the eth1 withdrawal address is set.
+func GenerateInteropValidators(spec *common.Spec, count uint64, tranchesDir string, ethWithdrawalAddress common.Eth1Address) ([]phase0.KickstartValidatorData, error) {
+	validators := make([]phase0.KickstartValidatorData, count)
+	var g errgroup.Group
+	g.SetLimit(10_000)
+	for i := uint64(0); i < count; i++ {
+		g.Go(func() error {
+			sk, err := InteropSecretKey(i)
+			if err != nil {
+				return err
+			}
+			pub, err := blsu.SkToPk(sk)
+			if err != nil {
+				return fmt.Errorf("failed to compute pubkey: %w", err)
+			}
+			data := &validators[i]
+			data.Pubkey = pub.Serialize()
+			if ethWithdrawalAddress == (common.Eth1Address{}) {
+				data.WithdrawalCredentials = sha256.Sum256(data.Pubkey[:])
+				data.WithdrawalCredentials[0] = common.BLS_WITHDRAWAL_PREFIX
+			} else {
+				data.WithdrawalCredentials[0] = common.ETH1_ADDRESS_WITHDRAWAL_PREFIX
+				copy(data.WithdrawalCredentials[12:], ethWithdrawalAddress[:])
+			}
+			data.Balance = spec.MAX_EFFECTIVE_BALANCE
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if err := outputPubkeys(filepath.Join(tranchesDir, "interop.txt"), validators); err != nil {
+		return nil, err
+	}
+	return validators, nil
+}
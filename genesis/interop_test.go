@@ -0,0 +1,49 @@
+package genesis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestInteropValidators(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	validators, err := GenerateInteropValidators(spec, 3, dir, common.Eth1Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The well-known interop pubkeys, as used by the client interop modes
+	expected := []string{
+		"0xa99a76ed7796f7be22d5b7e85deeb7c5677e88e511e0b337618f8c4eb61349b4bf2d153f649f7b53359fe8b94a38e44c",
+		"0xb89bebc699769726a318c8e9971bd3171297c61aea4a6578a7a4f94b547dcba5bac16a89108b6b6a1fe3695d1a874a0b",
+		"0xa3a32b0f8b4ddb83f1a0a853d81dd725dfe577d4f4c3db8ece52ce2b026eca84815c1a7e8e92a4de3d755733bf7e4a9b",
+	}
+	for i, exp := range expected {
+		if validators[i].Pubkey.String() != exp {
+			t.Errorf("interop validator %d: expected pubkey %s, got %s", i, exp, validators[i].Pubkey)
+		}
+		if validators[i].WithdrawalCredentials[0] != common.BLS_WITHDRAWAL_PREFIX {
+			t.Errorf("interop validator %d: expected BLS withdrawal credentials", i)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "interop.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(expected, "\n")+"\n" != string(data) {
+		t.Fatalf("unexpected interop tranche:\n%s", data)
+	}
+
+	validators, err = GenerateInteropValidators(spec, 1, dir, common.Eth1Address{0xde, 19: 0xad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validators[0].WithdrawalCredentials != (common.Root{0x01, 12: 0xde, 31: 0xad}) {
+		t.Fatalf("unexpected withdrawal credentials: %s", validators[0].WithdrawalCredentials)
+	}
+}
@@ -0,0 +1,285 @@
+package genesis
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Keystore is an EIP-2335 BLS12-381 keystore.
+type Keystore struct {
+	Crypto      KeystoreCrypto `json:"crypto"`
+	Description string         `json:"description"`
+	Pubkey      string         `json:"pubkey"`
+	Path        string         `json:"path"`
+	UUID        string         `json:"uuid"`
+	Version     uint           `json:"version"`
+}
+
+type KeystoreCrypto struct {
+	KDF      KeystoreModule `json:"kdf"`
+	Checksum KeystoreModule `json:"checksum"`
+	Cipher   KeystoreModule `json:"cipher"`
+}
+
+type KeystoreModule struct {
+	Function string          `json:"function"`
+	Params   json.RawMessage `json:"params"`
+	Message  string          `json:"message"`
+}
+
+type scryptParams struct {
+	DKLen int    `json:"dklen"`
+	N     int    `json:"n"`
+	P     int    `json:"p"`
+	R     int    `json:"r"`
+	Salt  string `json:"salt"`
+}
+
+type pbkdf2Params struct {
+	DKLen int    `json:"dklen"`
+	C     int    `json:"c"`
+	PRF   string `json:"prf"`
+	Salt  string `json:"salt"`
+}
+
+type cipherParams struct {
+	IV string `json:"iv"`
+}
+
+// EIP-2335 recommended KDF parameters.
+const (
+	keystoreScryptN          = 1 << 18
+	keystoreScryptR          = 8
+	keystoreScryptP          = 1
+	keystorePbkdf2Iterations = 1 << 18
+)
+
+// NewKeystore encrypts a secret key into a keystore, with the "scrypt" or "pbkdf2" KDF.
+// The path is the EIP-2334 derivation path of the key, and may be empty.
+func NewKeystore(sk *blsu.SecretKey, path string, password string, kdf string) (*Keystore, error) {
+	pw, err := keystorePassword(password)
+	if err != nil {
+		return nil, err
+	}
+	var salt [32]byte
+	var iv [16]byte
+	var id [16]byte
+	for _, b := range [][]byte{salt[:], iv[:], id[:]} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+	}
+	var k Keystore
+	var kdfParams any
+	switch kdf {
+	case "scrypt":
+		kdfParams = &scryptParams{DKLen: 32, N: keystoreScryptN, R: keystoreScryptR, P: keystoreScryptP, Salt: hex.EncodeToString(salt[:])}
+	case "pbkdf2":
+		kdfParams = &pbkdf2Params{DKLen: 32, C: keystorePbkdf2Iterations, PRF: "hmac-sha256", Salt: hex.EncodeToString(salt[:])}
+	default:
+		return nil, fmt.Errorf("unsupported kdf function %q, expected scrypt or pbkdf2", kdf)
+	}
+	k.Crypto.KDF = KeystoreModule{Function: kdf}
+	if k.Crypto.KDF.Params, err = json.Marshal(kdfParams); err != nil {
+		return nil, err
+	}
+	k.Crypto.Checksum = KeystoreModule{Function: "sha256", Params: json.RawMessage("{}")}
+	k.Crypto.Cipher = KeystoreModule{Function: "aes-128-ctr"}
+	if k.Crypto.Cipher.Params, err = json.Marshal(&cipherParams{IV: hex.EncodeToString(iv[:])}); err != nil {
+		return nil, err
+	}
+
+	key, err := k.Crypto.deriveKey(pw)
+	if err != nil {
+		return nil, err
+	}
+	secret := sk.Serialize()
+	cipherText, err := k.Crypto.cipher(key, secret[:])
+	if err != nil {
+		return nil, err
+	}
+	checksum := keystoreChecksum(key, cipherText)
+	k.Crypto.Cipher.Message = hex.EncodeToString(cipherText)
+	k.Crypto.Checksum.Message = hex.EncodeToString(checksum[:])
+
+	pub, err := blsu.SkToPk(sk)
+	if err != nil {
+		return nil, err
+	}
+	pubBytes := pub.Serialize()
+	k.Pubkey = hex.EncodeToString(pubBytes[:])
+	k.Path = path
+	// random (version 4) UUID
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	k.UUID = fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+	k.Version = 4
+	return &k, nil
+}
+
+// PubkeyBytes decodes the pubkey of the keystore, without decrypting the secret key.
+func (k *Keystore) PubkeyBytes() (common.BLSPubkey, error) {
+	var pub common.BLSPubkey
+	if k.Pubkey == "" {
+		return pub, errors.New("keystore has no pubkey")
+	}
+	err := decodeHexField("pubkey", k.Pubkey, pub[:])
+	return pub, err
+}
+
+// Decrypt decrypts the secret key of the keystore, and checks that it matches the pubkey of the keystore.
+func (k *Keystore) Decrypt(password string) (*blsu.SecretKey, error) {
+	if k.Version != 4 {
+		return nil, fmt.Errorf("unsupported keystore version %d", k.Version)
+	}
+	pw, err := keystorePassword(password)
+	if err != nil {
+		return nil, err
+	}
+	key, err := k.Crypto.deriveKey(pw)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cipher message: %w", err)
+	}
+
+	if k.Crypto.Checksum.Function != "sha256" {
+		return nil, fmt.Errorf("unsupported checksum function %q", k.Crypto.Checksum.Function)
+	}
+	checksum, err := hex.DecodeString(k.Crypto.Checksum.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum message: %w", err)
+	}
+	if expected := keystoreChecksum(key, cipherText); !bytes.Equal(checksum, expected[:]) {
+		return nil, errors.New("invalid keystore password")
+	}
+
+	secret, err := k.Crypto.cipher(key, cipherText)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("invalid secret key length %d", len(secret))
+	}
+	var sk blsu.SecretKey
+	if err := sk.Deserialize((*[32]byte)(secret)); err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+	pub, err := blsu.SkToPk(&sk)
+	if err != nil {
+		return nil, err
+	}
+	expectedPub, err := k.PubkeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	if pubBytes := pub.Serialize(); common.BLSPubkey(pubBytes) != expectedPub {
+		return nil, fmt.Errorf("secret key is for pubkey %s, not for keystore pubkey %s", common.BLSPubkey(pubBytes), expectedPub)
+	}
+	return &sk, nil
+}
+
+func (c *KeystoreCrypto) deriveKey(password []byte) ([]byte, error) {
+	switch c.KDF.Function {
+	case "scrypt":
+		var params scryptParams
+		if err := json.Unmarshal(c.KDF.Params, &params); err != nil {
+			return nil, fmt.Errorf("invalid scrypt params: %w", err)
+		}
+		salt, err := hex.DecodeString(params.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrypt salt: %w", err)
+		}
+		if params.DKLen < 32 {
+			return nil, fmt.Errorf("scrypt dklen %d is too short", params.DKLen)
+		}
+		return scrypt.Key(password, salt, params.N, params.R, params.P, params.DKLen)
+	case "pbkdf2":
+		var params pbkdf2Params
+		if err := json.Unmarshal(c.KDF.Params, &params); err != nil {
+			return nil, fmt.Errorf("invalid pbkdf2 params: %w", err)
+		}
+		if params.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %q", params.PRF)
+		}
+		salt, err := hex.DecodeString(params.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pbkdf2 salt: %w", err)
+		}
+		if params.DKLen < 32 {
+			return nil, fmt.Errorf("pbkdf2 dklen %d is too short", params.DKLen)
+		}
+		return pbkdf2.Key(password, salt, params.C, params.DKLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf function %q", c.KDF.Function)
+	}
+}
+
+// cipher en- or decrypts the message with AES-128-CTR, which is symmetric.
+func (c *KeystoreCrypto) cipher(key []byte, message []byte) ([]byte, error) {
+	if c.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported cipher function %q", c.Cipher.Function)
+	}
+	var params cipherParams
+	if err := json.Unmarshal(c.Cipher.Params, &params); err != nil {
+		return nil, fmt.Errorf("invalid cipher params: %w", err)
+	}
+	iv, err := hex.DecodeString(params.IV)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cipher iv: %w", err)
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid cipher iv length %d", len(iv))
+	}
+	out := make([]byte, len(message))
+	cipher.NewCTR(block, iv).XORKeyStream(out, message)
+	return out, nil
+}
+
+func keystoreChecksum(key []byte, cipherText []byte) [32]byte {
+	return sha256.Sum256(append(append([]byte{}, key[16:32]...), cipherText...))
+}
+
+// keystorePassword processes a password like EIP-2335: control codes are removed.
+// EIP-2335 also applies the NFKD unicode normalization, which is not supported here:
+// only ASCII passwords are accepted, which are not changed by NFKD.
+func keystorePassword(password string) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(password); i++ {
+		c := password[i]
+		if c >= 0x80 {
+			return nil, errors.New("non-ASCII keystore passwords are not supported")
+		}
+		if c < 0x20 || c == 0x7f {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// readKeystorePassword trims the trailing newline of a password file.
+func readKeystorePassword(data []byte) string {
+	return strings.TrimRight(string(data), "\r\n")
+}
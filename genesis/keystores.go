@@ -0,0 +1,280 @@
+package genesis
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// KeystoresSrc is a directory of EIP-2335 keystores to load validators from.
+type KeystoresSrc struct {
+	// Dir is scanned recursively for keystore JSON files. This covers the layouts of
+	// lighthouse (validators/0x<pubkey>/voting-keystore.json), teku (keys/<name>.json),
+	// nimbus (validators/0x<pubkey>/keystore.json) and prysm keystore imports (keystore-*.json).
+	Dir string
+	// SecretsDir is optional. If set, every keystore is decrypted with its password from the secrets dir,
+	// to verify that the keystore pubkey matches the secret key.
+	SecretsDir string
+	// WithdrawalCredentials of every keystore validator.
+	// If zero, BLS withdrawal credentials of the validator pubkey itself are used.
+	WithdrawalCredentials common.Root
+	// Balance of every keystore validator. MAX_EFFECTIVE_BALANCE if zero.
+	Balance common.Gwei
+}
+
+// LoadValidatorsFromKeystores loads a validator for each keystore in the keystores dir, in lexical path order.
+// Files that are not version 4 keystores, like deposit data files, are skipped.
+// The problems of all keystores are reported at once. The skipped files and progress are written to log, if not nil.
+func LoadValidatorsFromKeystores(spec *common.Spec, src *KeystoresSrc, log io.Writer) ([]phase0.KickstartValidatorData, error) {
+	validators, _, err := loadValidatorsFromKeystores(spec, src, logWriter(log))
+	return validators, err
+}
+
+// loadValidatorsFromKeystores also returns the path of the keystore of each validator.
+func loadValidatorsFromKeystores(spec *common.Spec, src *KeystoresSrc, log io.Writer) ([]phase0.KickstartValidatorData, []string, error) {
+	var paths []string
+	var keystores []*Keystore
+	var problems []error
+	err := filepath.WalkDir(src.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var keystore Keystore
+		if err := json.Unmarshal(data, &keystore); err != nil || keystore.Version != 4 {
+			fmt.Fprintf(log, "skipping %s, not an EIP-2335 keystore\n", path)
+			return nil
+		}
+		if keystore.Pubkey == "" {
+			problems = append(problems, fmt.Errorf("%s: keystore has no pubkey (a prysm wallet? import the EIP-2335 keystores instead)", path))
+			return nil
+		}
+		paths = append(paths, path)
+		keystores = append(keystores, &keystore)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan keystores dir: %w", err)
+	}
+
+	balance := src.Balance
+	if balance == 0 {
+		balance = spec.MAX_EFFECTIVE_BALANCE
+	}
+	validators := make([]phase0.KickstartValidatorData, len(keystores))
+	pubkeyPaths := make(map[common.BLSPubkey]string, len(keystores))
+	for i, keystore := range keystores {
+		pub, err := keystore.PubkeyBytes()
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", paths[i], err))
+			continue
+		}
+		if other, ok := pubkeyPaths[pub]; ok {
+			problems = append(problems, fmt.Errorf("%s: duplicate pubkey %s, also in %s", paths[i], pub, other))
+			continue
+		}
+		pubkeyPaths[pub] = paths[i]
+		validators[i].Pubkey = pub
+		validators[i].WithdrawalCredentials = src.WithdrawalCredentials
+		if src.WithdrawalCredentials == (common.Root{}) {
+			validators[i].WithdrawalCredentials = sha256.Sum256(pub[:])
+			validators[i].WithdrawalCredentials[0] = common.BLS_WITHDRAWAL_PREFIX
+		}
+		validators[i].Balance = balance
+	}
+
+	if src.SecretsDir != "" && len(problems) == 0 {
+		fmt.Fprintf(log, "decrypting %d keystores to verify pubkeys...\n", len(keystores))
+		decryptProblems := make([]error, len(keystores))
+		var g errgroup.Group
+		// The default scrypt parameters need 256 MiB of memory per keystore
+		g.SetLimit(4)
+		for i, keystore := range keystores {
+			g.Go(func() error {
+				password, err := findKeystorePassword(src.SecretsDir, paths[i], keystore.Pubkey)
+				if err != nil {
+					decryptProblems[i] = err
+				} else if _, err := keystore.Decrypt(password); err != nil {
+					decryptProblems[i] = fmt.Errorf("%s: %w", paths[i], err)
+				}
+				return nil
+			})
+		}
+		_ = g.Wait()
+		for _, err := range decryptProblems {
+			if err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, nil, errors.Join(problems...)
+	}
+	return validators, paths, nil
+}
+
+// findKeystorePassword reads the password of a keystore from the secrets dir, named after the pubkey
+// (lighthouse, nimbus), or after the keystore file (teku: <name>.txt), or shared by all keystores (prysm: password.txt).
+func findKeystorePassword(secretsDir string, keystorePath string, pubkey string) (string, error) {
+	pubkey = strings.TrimPrefix(pubkey, "0x")
+	name := strings.TrimSuffix(filepath.Base(keystorePath), ".json")
+	candidates := []string{"0x" + pubkey, pubkey, name + ".txt", name, "password.txt"}
+	for _, c := range candidates {
+		data, err := os.ReadFile(filepath.Join(secretsDir, c))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read password of keystore %s: %w", keystorePath, err)
+		}
+		return readKeystorePassword(data), nil
+	}
+	return "", fmt.Errorf("no password for keystore %s in secrets dir %s", keystorePath, secretsDir)
+}
+
+// KeystoresLayouts are the consensus-client directory layouts that keystores can be exported in.
+var KeystoresLayouts = []string{"lighthouse", "lodestar", "nimbus", "prysm", "teku"}
+
+// KeystoresExport configures the export of EIP-2335 keystores of the mnemonic validators.
+type KeystoresExport struct {
+	// Dir gets a sub-directory per mnemonic tranche, with the keystores and secrets in the Layout of a consensus client.
+	Dir string
+	// Layout is one of KeystoresLayouts:
+	//   - lighthouse: validators/0x<pubkey>/voting-keystore.json, secrets/0x<pubkey>
+	//   - lodestar: keystores/0x<pubkey>/voting-keystore.json, secrets/0x<pubkey>
+	//   - nimbus: validators/0x<pubkey>/keystore.json, secrets/0x<pubkey>
+	//   - prysm: keys/keystore-m_12381_3600_<index>_0_0.json, secrets/password.txt (one password for the tranche, to import)
+	//   - teku: keys/0x<pubkey>.json, secrets/0x<pubkey>.txt
+	Layout string
+	// KDF is "scrypt" or "pbkdf2".
+	KDF string
+	// PasswordFile is an optional file with the password of all keystores.
+	// Every keystore (prysm: every tranche) gets a random password if empty.
+	PasswordFile string
+}
+
+// Check the layout and KDF before deriving any keys.
+func (e *KeystoresExport) Check() error {
+	if !slices.Contains(KeystoresLayouts, e.Layout) {
+		return fmt.Errorf("unknown keystores layout %q, expected one of %s", e.Layout, strings.Join(KeystoresLayouts, ", "))
+	}
+	if e.KDF != "scrypt" && e.KDF != "pbkdf2" {
+		return fmt.Errorf("unknown keystores kdf %q, expected scrypt or pbkdf2", e.KDF)
+	}
+	return nil
+}
+
+// WriteTranche encrypts and writes the keystores of the validators of a mnemonic tranche.
+// The indices are the validator indices within the mnemonic, of the EIP-2334 derivation paths of the secret keys.
+// The progress is written to log, if not nil.
+func (e *KeystoresExport) WriteTranche(tranche string, sks []*blsu.SecretKey, indices []uint64, log io.Writer) error {
+	log = logWriter(log)
+	dir := filepath.Join(e.Dir, tranche)
+	secretsDir := filepath.Join(dir, "secrets")
+	if err := os.MkdirAll(secretsDir, 0700); err != nil {
+		return err
+	}
+	var password string
+	if e.PasswordFile != "" {
+		data, err := os.ReadFile(e.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read keystores password: %w", err)
+		}
+		password = readKeystorePassword(data)
+	}
+	if e.Layout == "prysm" {
+		if password == "" {
+			password = randomKeystorePassword()
+		}
+		if err := os.WriteFile(filepath.Join(secretsDir, "password.txt"), []byte(password), 0600); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(log, "writing %d %s keystores to %s...\n", len(sks), e.Layout, dir)
+	var g errgroup.Group
+	// The default scrypt parameters need 256 MiB of memory per keystore
+	g.SetLimit(4)
+	var prog int32
+	for i, sk := range sks {
+		g.Go(func() error {
+			pw := password
+			if pw == "" {
+				pw = randomKeystorePassword()
+			}
+			k, err := NewKeystore(sk, validatorKeyName(indices[i]), pw, e.KDF)
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(k, "", "  ")
+			if err != nil {
+				return err
+			}
+			pub := "0x" + k.Pubkey
+			var keystorePath, secretPath string
+			switch e.Layout {
+			case "lighthouse":
+				keystorePath = filepath.Join(dir, "validators", pub, "voting-keystore.json")
+				secretPath = filepath.Join(secretsDir, pub)
+			case "lodestar":
+				keystorePath = filepath.Join(dir, "keystores", pub, "voting-keystore.json")
+				secretPath = filepath.Join(secretsDir, pub)
+			case "nimbus":
+				keystorePath = filepath.Join(dir, "validators", pub, "keystore.json")
+				secretPath = filepath.Join(secretsDir, pub)
+			case "prysm":
+				keystorePath = filepath.Join(dir, "keys", fmt.Sprintf("keystore-m_12381_3600_%d_0_0.json", indices[i]))
+			case "teku":
+				keystorePath = filepath.Join(dir, "keys", pub+".json")
+				secretPath = filepath.Join(secretsDir, pub+".txt")
+			}
+			if err := os.MkdirAll(filepath.Dir(keystorePath), 0700); err != nil {
+				return err
+			}
+			if err := os.WriteFile(keystorePath, data, 0600); err != nil {
+				return err
+			}
+			if secretPath != "" {
+				if err := os.WriteFile(secretPath, []byte(pw), 0600); err != nil {
+					return err
+				}
+			}
+			if count := atomic.AddInt32(&prog, 1); count%100 == 0 {
+				fmt.Fprintf(log, "...keystore %d/%d\n", count, len(sks))
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func randomKeystorePassword() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
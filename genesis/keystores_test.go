@@ -0,0 +1,211 @@
+package genesis
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// testKeystore encrypts insecure secret key i+1, with cheap pbkdf2 parameters.
+func testKeystore(t *testing.T, i int, password string) (*Keystore, common.BLSPubkey) {
+	var skBytes [32]byte
+	binary.BigEndian.PutUint64(skBytes[24:], uint64(i+1))
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&skBytes); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := blsu.SkToPk(&sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubBytes := common.BLSPubkey(pub.Serialize())
+	salt := []byte{byte(i), 1, 2, 3}
+	iv := make([]byte, 16)
+	kdfParams, _ := json.Marshal(&pbkdf2Params{DKLen: 32, C: 2, PRF: "hmac-sha256", Salt: hex.EncodeToString(salt)})
+	cipherParams, _ := json.Marshal(&cipherParams{IV: hex.EncodeToString(iv)})
+	k := &Keystore{
+		Crypto: KeystoreCrypto{
+			KDF:      KeystoreModule{Function: "pbkdf2", Params: kdfParams},
+			Checksum: KeystoreModule{Function: "sha256", Params: json.RawMessage("{}")},
+			Cipher:   KeystoreModule{Function: "aes-128-ctr", Params: cipherParams},
+		},
+		Pubkey:  hex.EncodeToString(pubBytes[:]),
+		Version: 4,
+	}
+	key := pbkdf2.Key([]byte(password), salt, 2, 32, sha256.New)
+	cipherText, err := k.Crypto.cipher(key, skBytes[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := keystoreChecksum(key, cipherText)
+	k.Crypto.Cipher.Message = hex.EncodeToString(cipherText)
+	k.Crypto.Checksum.Message = hex.EncodeToString(checksum[:])
+	return k, pubBytes
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestKeystore(t *testing.T, path string, k *Keystore) {
+	data, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, path, data)
+}
+
+func TestLoadValidatorsFromKeystores(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	keysDir := filepath.Join(dir, "keys")
+	secretsDir := filepath.Join(dir, "secrets")
+
+	// lighthouse layout
+	lh, lhPub := testKeystore(t, 0, "lighthouse password")
+	writeTestKeystore(t, filepath.Join(keysDir, "validators", lhPub.String(), "voting-keystore.json"), lh)
+	writeTestFile(t, filepath.Join(secretsDir, lhPub.String()), []byte("lighthouse password"))
+	// teku layout, with a trailing newline in the password file
+	teku, tekuPub := testKeystore(t, 1, "teku password")
+	writeTestKeystore(t, filepath.Join(keysDir, "teku", "validator_1.json"), teku)
+	writeTestFile(t, filepath.Join(secretsDir, "validator_1.txt"), []byte("teku password\n"))
+	// not a keystore
+	writeTestFile(t, filepath.Join(keysDir, "deposit_data-1.json"), []byte(`[{"pubkey": "00"}]`))
+
+	src := &KeystoresSrc{Dir: keysDir}
+	validators, err := LoadValidatorsFromKeystores(spec, src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 2 {
+		t.Fatalf("expected 2 validators, got %d", len(validators))
+	}
+	// lexical path order
+	if validators[0].Pubkey != tekuPub || validators[1].Pubkey != lhPub {
+		t.Fatalf("unexpected pubkeys: %s, %s", validators[0].Pubkey, validators[1].Pubkey)
+	}
+	if validators[0].Balance != spec.MAX_EFFECTIVE_BALANCE || validators[0].WithdrawalCredentials[0] != common.BLS_WITHDRAWAL_PREFIX {
+		t.Fatalf("unexpected validator: %+v", validators[0])
+	}
+
+	src.SecretsDir = secretsDir
+	src.WithdrawalCredentials = common.Root{COMPOUNDING_WITHDRAWAL_PREFIX, 31: 1}
+	src.Balance = 64_000_000_000
+	validators, err = LoadValidatorsFromKeystores(spec, src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validators[1].Balance != 64_000_000_000 || validators[1].WithdrawalCredentials != src.WithdrawalCredentials {
+		t.Fatalf("unexpected validator: %+v", validators[1])
+	}
+
+	writeTestFile(t, filepath.Join(secretsDir, "validator_1.txt"), []byte("wrong password"))
+	if _, err := LoadValidatorsFromKeystores(spec, src, nil); err == nil || !strings.Contains(err.Error(), "invalid keystore password") {
+		t.Fatalf("expected invalid password error, got %v", err)
+	}
+
+	// a keystore for a different key than its pubkey
+	other, _ := testKeystore(t, 2, "teku password")
+	other.Pubkey = teku.Pubkey
+	writeTestFile(t, filepath.Join(secretsDir, "validator_1.txt"), []byte("teku password"))
+	writeTestKeystore(t, filepath.Join(keysDir, "teku", "validator_1.json"), other)
+	if _, err := LoadValidatorsFromKeystores(spec, src, nil); err == nil || !strings.Contains(err.Error(), "not for keystore pubkey") {
+		t.Fatalf("expected pubkey mismatch error, got %v", err)
+	}
+}
+
+func TestExportKeystores(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	mnemonicsPath := filepath.Join(dir, "mnemonics.yaml")
+	writeTestFile(t, mnemonicsPath, []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 2
+- mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+  count: 1
+`))
+	passwordPath := filepath.Join(dir, "password.txt")
+	writeTestFile(t, passwordPath, []byte("shared password\n"))
+
+	for _, layout := range []string{"teku", "prysm"} {
+		t.Run(layout, func(t *testing.T) {
+			tranchesDir := filepath.Join(dir, layout, "tranches")
+			if err := os.MkdirAll(tranchesDir, 0777); err != nil {
+				t.Fatal(err)
+			}
+			export := &KeystoresExport{Dir: filepath.Join(dir, layout, "keystores"), Layout: layout, KDF: "pbkdf2"}
+			if layout == "prysm" {
+				export.PasswordFile = passwordPath
+			}
+			validators, err := GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, tranchesDir, common.Eth1Address{}, export, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// every tranche decrypts to the keys of the genesis validators
+			for m, tranche := range [][]int{{0, 1}, {2}} {
+				loaded, err := LoadValidatorsFromKeystores(spec, &KeystoresSrc{
+					Dir:        filepath.Join(export.Dir, fmt.Sprintf("tranche_%04d", m), "keys"),
+					SecretsDir: filepath.Join(export.Dir, fmt.Sprintf("tranche_%04d", m), "secrets"),
+				}, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(loaded) != len(tranche) {
+					t.Fatalf("tranche %d: expected %d keystores, got %d", m, len(tranche), len(loaded))
+				}
+				for _, v := range loaded {
+					if !slices.ContainsFunc(tranche, func(i int) bool { return validators[i].Pubkey == v.Pubkey }) {
+						t.Fatalf("tranche %d: unexpected keystore pubkey %s", m, v.Pubkey)
+					}
+				}
+			}
+		})
+	}
+
+	export := &KeystoresExport{Dir: filepath.Join(dir, "bad"), Layout: "vouch", KDF: "scrypt"}
+	if _, err := GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, dir, common.Eth1Address{}, export, "", nil); err == nil {
+		t.Fatal("expected unknown layout error")
+	}
+}
+
+func TestNewKeystoreScrypt(t *testing.T) {
+	var skBytes [32]byte
+	skBytes[31] = 1
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&skBytes); err != nil {
+		t.Fatal(err)
+	}
+	k, err := NewKeystore(&sk, "m/12381/3600/0/0/0", "password", "scrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := k.Decrypt("pass\x7fword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Serialize() != skBytes {
+		t.Fatal("decrypted secret key does not match")
+	}
+	if _, err := k.Decrypt("wrong"); err == nil {
+		t.Fatal("expected invalid password error")
+	}
+}
@@ -0,0 +1,41 @@
+package genesis
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// Metadata records how a genesis state was created, for reproducibility.
+type Metadata struct {
+	Fork                  string           `json:"fork" yaml:"fork"`
+	GenesisTime           common.Timestamp `json:"genesis_time" yaml:"genesis_time"`
+	GenesisValidatorsRoot common.Root      `json:"genesis_validators_root" yaml:"genesis_validators_root"`
+	ValidatorCount        uint64           `json:"validator_count" yaml:"validator_count"`
+	// Eth1Block is the execution-layer block embedded in the state, nil if there is none.
+	Eth1Block  *Eth1BlockMetadata `json:"eth1_block,omitempty" yaml:"eth1_block,omitempty"`
+	ShadowFork bool               `json:"shadow_fork" yaml:"shadow_fork"`
+}
+
+type Eth1BlockMetadata struct {
+	Number    uint64      `json:"number" yaml:"number"`
+	Hash      common.Root `json:"hash" yaml:"hash"`
+	Timestamp uint64      `json:"timestamp" yaml:"timestamp"`
+}
+
+// Metadata summarizes the result, with the execution-layer block that was chosen.
+func (r *Result) Metadata() *Metadata {
+	m := &Metadata{
+		Fork:                  r.Fork.Name,
+		GenesisTime:           r.GenesisTime,
+		GenesisValidatorsRoot: r.GenesisValidatorsRoot,
+		ValidatorCount:        r.ValidatorCount,
+		ShadowFork:            r.ShadowFork,
+	}
+	if r.Eth1Block != nil {
+		m.Eth1Block = &Eth1BlockMetadata{
+			Number:    r.Eth1Block.NumberU64(),
+			Hash:      common.Root(r.Eth1Block.Hash()),
+			Timestamp: r.Eth1Block.Time(),
+		}
+	}
+	return m
+}
@@ -0,0 +1,158 @@
+package genesis
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/holiman/uint256"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+func BellatrixPayloadHeader(spec *common.Spec, eth1Block *types.Block, prevRandaoMix common.Bytes32) (*bellatrix.ExecutionPayloadHeader, error) {
+	extra := eth1Block.Extra()
+	if len(extra) > common.MAX_EXTRA_DATA_BYTES {
+		return nil, fmt.Errorf("extra data is %d bytes, max is %d", len(extra), common.MAX_EXTRA_DATA_BYTES)
+	}
+
+	baseFee, _ := uint256.FromBig(eth1Block.BaseFee())
+
+	txsRoot, err := payloadTransactionsRoot(spec, eth1Block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bellatrix.ExecutionPayloadHeader{
+		ParentHash:       common.Root(eth1Block.ParentHash()),
+		FeeRecipient:     common.Eth1Address(eth1Block.Coinbase()),
+		StateRoot:        common.Bytes32(eth1Block.Root()),
+		ReceiptsRoot:     common.Bytes32(eth1Block.ReceiptHash()),
+		LogsBloom:        common.LogsBloom(eth1Block.Bloom()),
+		PrevRandao:       prevRandaoMix,
+		BlockNumber:      view.Uint64View(eth1Block.NumberU64()),
+		GasLimit:         view.Uint64View(eth1Block.GasLimit()),
+		GasUsed:          view.Uint64View(eth1Block.GasUsed()),
+		Timestamp:        common.Timestamp(eth1Block.Time()),
+		ExtraData:        extra,
+		BaseFeePerGas:    view.Uint256View(*baseFee),
+		BlockHash:        common.Root(eth1Block.Hash()),
+		TransactionsRoot: txsRoot,
+	}, nil
+}
+
+func CapellaPayloadHeader(spec *common.Spec, eth1Block *types.Block, prevRandaoMix common.Bytes32) (*capella.ExecutionPayloadHeader, error) {
+	h, err := BellatrixPayloadHeader(spec, eth1Block, prevRandaoMix)
+	if err != nil {
+		return nil, err
+	}
+	return &capella.ExecutionPayloadHeader{
+		ParentHash:       h.ParentHash,
+		FeeRecipient:     h.FeeRecipient,
+		StateRoot:        h.StateRoot,
+		ReceiptsRoot:     h.ReceiptsRoot,
+		LogsBloom:        h.LogsBloom,
+		PrevRandao:       h.PrevRandao,
+		BlockNumber:      h.BlockNumber,
+		GasLimit:         h.GasLimit,
+		GasUsed:          h.GasUsed,
+		Timestamp:        h.Timestamp,
+		ExtraData:        h.ExtraData,
+		BaseFeePerGas:    h.BaseFeePerGas,
+		BlockHash:        h.BlockHash,
+		TransactionsRoot: h.TransactionsRoot,
+		WithdrawalsRoot:  payloadWithdrawalsRoot(spec, eth1Block),
+	}, nil
+}
+
+func DenebPayloadHeader(spec *common.Spec, eth1Block *types.Block, prevRandaoMix common.Bytes32) (*deneb.ExecutionPayloadHeader, error) {
+	if eth1Block.BlobGasUsed() == nil {
+		return nil, errors.New("execution-layer Block has missing blob-gas-used field")
+	}
+	if eth1Block.ExcessBlobGas() == nil {
+		return nil, errors.New("execution-layer Block has missing excess-blob-gas field")
+	}
+	h, err := CapellaPayloadHeader(spec, eth1Block, prevRandaoMix)
+	if err != nil {
+		return nil, err
+	}
+	return &deneb.ExecutionPayloadHeader{
+		ParentHash:       h.ParentHash,
+		FeeRecipient:     h.FeeRecipient,
+		StateRoot:        h.StateRoot,
+		ReceiptsRoot:     h.ReceiptsRoot,
+		LogsBloom:        h.LogsBloom,
+		PrevRandao:       h.PrevRandao,
+		BlockNumber:      h.BlockNumber,
+		GasLimit:         h.GasLimit,
+		GasUsed:          h.GasUsed,
+		Timestamp:        h.Timestamp,
+		ExtraData:        h.ExtraData,
+		BaseFeePerGas:    h.BaseFeePerGas,
+		BlockHash:        h.BlockHash,
+		TransactionsRoot: h.TransactionsRoot,
+		WithdrawalsRoot:  h.WithdrawalsRoot,
+		BlobGasUsed:      view.Uint64View(*eth1Block.BlobGasUsed()),
+		ExcessBlobGas:    view.Uint64View(*eth1Block.ExcessBlobGas()),
+	}, nil
+}
+
+// Electra has the same deneb execution-payload-header format,
+// the new requests-root is not part of the beacon ExecutionPayloadHeader type
+func ElectraPayloadHeader(spec *common.Spec, eth1Block *types.Block, prevRandaoMix common.Bytes32) (*deneb.ExecutionPayloadHeader, error) {
+	// Sanity-check the new requests-hash
+	if reqHash := eth1Block.RequestsHash(); reqHash == nil {
+		return nil, errors.New("pectra execution-layer block has missing requests-hash attribute")
+	} else if *reqHash != types.EmptyRequestsHash {
+		return nil, fmt.Errorf("expected requests-hash of empty requests-list in genesis block (%s) but got %s instead", types.EmptyRequestsHash, *reqHash)
+	}
+	return DenebPayloadHeader(spec, eth1Block, prevRandaoMix)
+}
+
+// Compute the SSZ hash-tree-root of the transactions,
+// since that is what we put as transactions_root in the CL execution-payload.
+// Not to be confused with the legacy MPT root in the EL block header.
+func payloadTransactionsRoot(spec *common.Spec, eth1Block *types.Block) (common.Root, error) {
+	clTransactions := make(common.PayloadTransactions, len(eth1Block.Transactions()))
+	for i, tx := range eth1Block.Transactions() {
+		opaqueTx, err := tx.MarshalBinary()
+		if err != nil {
+			return common.Root{}, fmt.Errorf("failed to encode tx %d: %w", i, err)
+		}
+		clTransactions[i] = opaqueTx
+	}
+	return clTransactions.HashTreeRoot(spec, tree.GetHashFn()), nil
+}
+
+// Compute the SSZ hash-tree-root of the withdrawals,
+// since that is what we put as withdrawals_root in the CL execution-payload.
+// Not to be confused with the legacy MPT root in the EL block header.
+func payloadWithdrawalsRoot(spec *common.Spec, eth1Block *types.Block) common.Root {
+	if eth1Block.Withdrawals() == nil {
+		return common.Root{}
+	}
+	clWithdrawals := make(common.Withdrawals, len(eth1Block.Withdrawals()))
+	for i, withdrawal := range eth1Block.Withdrawals() {
+		clWithdrawals[i] = common.Withdrawal{
+			Index:          common.WithdrawalIndex(withdrawal.Index),
+			ValidatorIndex: common.ValidatorIndex(withdrawal.Validator),
+			Address:        common.Eth1Address(withdrawal.Address),
+			Amount:         common.Gwei(withdrawal.Amount),
+		}
+	}
+	return clWithdrawals.HashTreeRoot(spec, tree.GetHashFn())
+}
+
+func bigIntToBytes32(n *big.Int) [32]byte {
+	var result [32]byte
+	b := n.Bytes()
+	copy(result[32-len(b):], b)
+	return result
+}
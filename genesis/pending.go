@@ -0,0 +1,118 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/eth2-testnet-genesis/fulu"
+)
+
+// UNSET_DEPOSIT_REQUESTS_START_INDEX is the deposit_requests_start_index of the spec genesis state:
+// Eth1 bridge deposits are processed until the first deposit request is included.
+const UNSET_DEPOSIT_REQUESTS_START_INDEX = math.MaxUint64
+
+// Electra state fields that zrnt does not expose with setters.
+const (
+	electraPendingDepositsField           = 34
+	electraPendingPartialWithdrawalsField = 35
+	electraPendingConsolidationsField     = 36
+)
+
+// PendingQueues are the Electra queues to pre-populate in the genesis state,
+// to test queue processing from the first slot.
+type PendingQueues struct {
+	Deposits           common.PendingDeposits           `json:"pending_deposits" yaml:"pending_deposits"`
+	PartialWithdrawals common.PendingPartialWithdrawals `json:"pending_partial_withdrawals" yaml:"pending_partial_withdrawals"`
+	Consolidations     common.PendingConsolidations     `json:"pending_consolidations" yaml:"pending_consolidations"`
+}
+
+// LoadPendingQueues reads the pending queues from a JSON (.json extension) or YAML file.
+func LoadPendingQueues(srcPath string) (*PendingQueues, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	var queues PendingQueues
+	if strings.EqualFold(filepath.Ext(srcPath), ".json") {
+		err = json.Unmarshal(data, &queues)
+	} else {
+		err = yaml.Unmarshal(data, &queues)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pending queues %s: %w", srcPath, err)
+	}
+	return &queues, nil
+}
+
+// SetPendingQueues puts the pending queues in the state.
+// Validator indices in the partial withdrawals and consolidations must exist in the state.
+func SetPendingQueues(spec *common.Spec, state *electra.BeaconStateView, queues *PendingQueues) error {
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	valCount, err := vals.ValidatorCount()
+	if err != nil {
+		return err
+	}
+	for i, w := range queues.PartialWithdrawals {
+		if uint64(w.ValidatorIndex) >= valCount {
+			return fmt.Errorf("pending partial withdrawal %d: unknown validator %d", i, w.ValidatorIndex)
+		}
+	}
+	for i, c := range queues.Consolidations {
+		if uint64(c.SourceIndex) >= valCount || uint64(c.TargetIndex) >= valCount {
+			return fmt.Errorf("pending consolidation %d: unknown validator %d or %d", i, c.SourceIndex, c.TargetIndex)
+		}
+		if c.SourceIndex == c.TargetIndex {
+			return fmt.Errorf("pending consolidation %d: source and target are both validator %d", i, c.SourceIndex)
+		}
+	}
+	if err := setStateList(spec, state, electraPendingDepositsField, common.PendingDepositsType(spec), &queues.Deposits); err != nil {
+		return fmt.Errorf("failed to set pending deposits: %w", err)
+	}
+	if err := setStateList(spec, state, electraPendingPartialWithdrawalsField, common.PendingPartialWithdrawalsType(spec), &queues.PartialWithdrawals); err != nil {
+		return fmt.Errorf("failed to set pending partial withdrawals: %w", err)
+	}
+	if err := setStateList(spec, state, electraPendingConsolidationsField, common.PendingConsolidationsType(spec), &queues.Consolidations); err != nil {
+		return fmt.Errorf("failed to set pending consolidations: %w", err)
+	}
+	return nil
+}
+
+// electraStateView returns the Electra part of an Electra or later state.
+func electraStateView(state common.BeaconState) *electra.BeaconStateView {
+	switch st := state.(type) {
+	case *electra.BeaconStateView:
+		return st
+	case *fulu.BeaconStateView:
+		return st.BeaconStateView
+	default:
+		panic(fmt.Errorf("unexpected state type %T", state))
+	}
+}
+
+// setStateList converts the list to a tree-backed view by SSZ-encoding it, and sets it in the state.
+func setStateList(spec *common.Spec, state *electra.BeaconStateView, field uint64, typ view.ListTypeDef, list common.SpecObj) error {
+	var buf bytes.Buffer
+	if err := list.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		return err
+	}
+	v, err := typ.Deserialize(codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len())))
+	if err != nil {
+		return err
+	}
+	return state.Set(field, v)
+}
@@ -0,0 +1,130 @@
+package genesis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+)
+
+const testPendingQueuesYAML = `
+pending_deposits:
+  - pubkey: "0x9824e447621e4b3bca7794b91c664cc0b43322a70b1881b2f804e3a990a3965a64bfe7f098cb4c0396cd0c89218de0b4"
+    withdrawal_credentials: "0x001547805ff0547da9e51a7463a6a0c603eeda01dd930f7016185f0642b9ecaf"
+    amount: 32000000000
+    signature: "0x000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"
+    slot: 0
+pending_partial_withdrawals:
+  - validator_index: 3
+    amount: 1000000000
+    withdrawable_epoch: 10
+pending_consolidations:
+  - source_index: 1
+    target_index: 2
+`
+
+const testPendingQueuesJSON = `{
+  "pending_deposits": [],
+  "pending_partial_withdrawals": [{"validator_index": "3", "amount": "1000000000", "withdrawable_epoch": "10"}],
+  "pending_consolidations": [{"source_index": "1", "target_index": "2"}]
+}`
+
+func TestBuildElectraPendingQueues(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name     string
+		content  string
+		deposits int
+	}{
+		{"queues.yaml", testPendingQueuesYAML, 1},
+		{"queues.json", testPendingQueuesJSON, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			queues, err := LoadPendingQueues(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queues.Deposits) != tc.deposits || len(queues.PartialWithdrawals) != 1 || len(queues.Consolidations) != 1 {
+				t.Fatalf("unexpected queues: %+v", queues)
+			}
+			if w := queues.PartialWithdrawals[0]; w.ValidatorIndex != 3 || w.Amount != 1_000_000_000 || w.WithdrawableEpoch != 10 {
+				t.Fatalf("unexpected partial withdrawal: %+v", w)
+			}
+			res, err := Build(context.Background(), &Options{
+				Spec:                      spec,
+				Fork:                      "electra",
+				Eth1BlockTimestamp:        1000,
+				Validators:                testValidators(t, spec, 64),
+				DepositRequestsStartIndex: UNSET_DEPOSIT_REQUESTS_START_INDEX,
+				PendingQueuesFilePath:     path,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := res.State.(*electra.BeaconStateView)
+			startIndex, err := state.DepositRequestsStartIndex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if startIndex != UNSET_DEPOSIT_REQUESTS_START_INDEX {
+				t.Fatalf("unexpected deposit requests start index: %d", startIndex)
+			}
+			hFn := tree.GetHashFn()
+			for field, exp := range map[uint64]tree.Root{
+				electraPendingDepositsField:           queues.Deposits.HashTreeRoot(spec, hFn),
+				electraPendingPartialWithdrawalsField: queues.PartialWithdrawals.HashTreeRoot(spec, hFn),
+				electraPendingConsolidationsField:     queues.Consolidations.HashTreeRoot(spec, hFn),
+			} {
+				v, err := state.Get(field)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if root := v.HashTreeRoot(hFn); root != exp {
+					t.Errorf("field %d: expected root %s, got %s", field, exp, root)
+				}
+			}
+		})
+	}
+}
+
+func TestSetPendingQueuesUnknownValidator(t *testing.T) {
+	spec := configs.Minimal
+	res, err := Build(context.Background(), &Options{
+		Spec:               spec,
+		Fork:               "electra",
+		Eth1BlockTimestamp: 1000,
+		Validators:         testValidators(t, spec, 4),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := res.State.(*electra.BeaconStateView)
+	queues := &PendingQueues{Consolidations: common.PendingConsolidations{{SourceIndex: 1, TargetIndex: 4}}}
+	if err := SetPendingQueues(spec, state, queues); err == nil {
+		t.Fatal("expected error for unknown consolidation target")
+	}
+}
+
+func TestBuildPendingQueuesBeforeElectra(t *testing.T) {
+	spec := configs.Minimal
+	_, err := Build(context.Background(), &Options{
+		Spec:                      spec,
+		Fork:                      "deneb",
+		Eth1BlockTimestamp:        1000,
+		Validators:                testValidators(t, spec, 4),
+		DepositRequestsStartIndex: 5,
+	})
+	if err == nil {
+		t.Fatal("expected deposit requests start index to be rejected before electra")
+	}
+}
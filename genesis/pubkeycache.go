@@ -0,0 +1,250 @@
+package genesis
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+
+	blshd "github.com/protolambda/bls12-381-hd"
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// The kinds of keys derived per validator key index.
+const (
+	signingKeyKind = iota
+	withdrawalKeyKind
+)
+
+// pubkeyCacheFiles are the cache files of each key kind, named after the derivation path, with i for the key index.
+var pubkeyCacheFiles = [...]string{
+	signingKeyKind:    "m_12381_3600_i_0_0.pubkeys",
+	withdrawalKeyKind: "m_12381_3600_i_0.pubkeys",
+}
+
+func keyPath(kind int, index uint64) string {
+	if kind == withdrawalKeyKind {
+		return withdrawalKeyName(index)
+	}
+	return validatorKeyName(index)
+}
+
+// pubkeyCacheEntrySize is the size of a cache entry: the 48-byte pubkey, and an 8-byte checksum.
+const pubkeyCacheEntrySize = 48 + 8
+
+// pubkeyCacheChecksum is the checksum of a cache entry: the first 8 bytes of the hash of the key index and pubkey.
+// An entry with zero bytes, or a torn or corrupted write, or an entry at the wrong key index, does not match its checksum.
+func pubkeyCacheChecksum(index uint64, pub *common.BLSPubkey) (out [8]byte) {
+	var buf [8 + 48]byte
+	binary.LittleEndian.PutUint64(buf[:8], index)
+	copy(buf[8:], pub[:])
+	h := sha256.Sum256(buf[:])
+	copy(out[:], h[:8])
+	return out
+}
+
+// mnemonicPubkeyCache caches the derived pubkeys of a mnemonic (and passphrase) on disk, never the secret keys.
+// The cache dir has a directory per mnemonic, named after the hash of the seed, with a file per key kind.
+// A file is a dense array of entries by key index, each a 48-byte pubkey and a checksum, see pubkeyCacheChecksum.
+// Entries that do not match their checksum, like the zero bytes of pubkeys that are not cached yet, are cache misses.
+type mnemonicPubkeyCache struct {
+	files [len(pubkeyCacheFiles)]*os.File
+}
+
+func openMnemonicPubkeyCache(cacheDir string, seed []byte) (*mnemonicPubkeyCache, error) {
+	h := sha256.Sum256(seed)
+	dir := filepath.Join(cacheDir, hex.EncodeToString(h[:]))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create pubkey cache dir: %w", err)
+	}
+	var c mnemonicPubkeyCache
+	for kind, name := range pubkeyCacheFiles {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to open pubkey cache: %w", err)
+		}
+		c.files[kind] = f
+	}
+	return &c, nil
+}
+
+func (c *mnemonicPubkeyCache) Close() error {
+	var errs []error
+	for _, f := range c.files {
+		if f != nil {
+			errs = append(errs, f.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// get a cached pubkey. Entries that do not match their checksum are not cached. Safe for concurrent use.
+func (c *mnemonicPubkeyCache) get(kind int, index uint64) (pub common.BLSPubkey, ok bool, err error) {
+	var entry [pubkeyCacheEntrySize]byte
+	n, err := c.files[kind].ReadAt(entry[:], int64(index)*pubkeyCacheEntrySize)
+	if err == io.EOF || (err == nil && n < len(entry)) {
+		return pub, false, nil
+	}
+	if err != nil {
+		return pub, false, fmt.Errorf("failed to read pubkey cache: %w", err)
+	}
+	copy(pub[:], entry[:48])
+	if pubkeyCacheChecksum(index, &pub) != [8]byte(entry[48:]) {
+		return common.BLSPubkey{}, false, nil
+	}
+	return pub, true, nil
+}
+
+// put a pubkey in the cache, or remove it with the zero pubkey. Safe for concurrent use.
+func (c *mnemonicPubkeyCache) put(kind int, index uint64, pub common.BLSPubkey) error {
+	var entry [pubkeyCacheEntrySize]byte
+	if pub != (common.BLSPubkey{}) {
+		copy(entry[:48], pub[:])
+		checksum := pubkeyCacheChecksum(index, &pub)
+		copy(entry[48:], checksum[:])
+	}
+	if _, err := c.files[kind].WriteAt(entry[:], int64(index)*pubkeyCacheEntrySize); err != nil {
+		return fmt.Errorf("failed to write pubkey cache: %w", err)
+	}
+	return nil
+}
+
+// pubkey returns the cached pubkey, or derives it and adds it to the cache. The cache may be nil.
+func (c *mnemonicPubkeyCache) pubkey(seed []byte, kind int, index uint64) (pub common.BLSPubkey, cached bool, err error) {
+	if c != nil {
+		if pub, ok, err := c.get(kind, index); err != nil || ok {
+			return pub, ok, err
+		}
+	}
+	_, pub, err = deriveKey(seed, keyPath(kind, index))
+	if err != nil {
+		return pub, false, err
+	}
+	if c != nil {
+		err = c.put(kind, index, pub)
+	}
+	return pub, false, err
+}
+
+// deriveKey derives the EIP-2333 secret key at the EIP-2334 path, and its pubkey.
+func deriveKey(seed []byte, path string) (*blsu.SecretKey, common.BLSPubkey, error) {
+	skBytes, err := blshd.SecretKeyFromHD(seed, path)
+	if err != nil {
+		return nil, common.BLSPubkey{}, err
+	}
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(skBytes); err != nil {
+		return nil, common.BLSPubkey{}, fmt.Errorf("failed to decode derived secret key: %w", err)
+	}
+	pub, err := blsu.SkToPk(&sk)
+	if err != nil {
+		return nil, common.BLSPubkey{}, fmt.Errorf("failed to compute pubkey: %w", err)
+	}
+	return &sk, pub.Serialize(), nil
+}
+
+// WarmPubkeyCache derives the pubkeys of the validators of the mnemonics that are not cached yet.
+// Withdrawal pubkeys are only derived for validators with BLS withdrawal credentials.
+// The counts per mnemonic are written to log, if not nil.
+func WarmPubkeyCache(mnemonicsConfigPath string, cacheDir string, ethWithdrawalAddress common.Eth1Address, log io.Writer) error {
+	return walkPubkeyCache(mnemonicsConfigPath, cacheDir, ethWithdrawalAddress, false, logWriter(log))
+}
+
+// VerifyPubkeyCache derives the pubkeys of the validators of the mnemonics again, and compares them to the cached pubkeys.
+// Mismatching pubkeys are removed from the cache, so they are derived again by the next build.
+// The mismatches and the counts per mnemonic are written to log, if not nil.
+func VerifyPubkeyCache(mnemonicsConfigPath string, cacheDir string, ethWithdrawalAddress common.Eth1Address, log io.Writer) error {
+	return walkPubkeyCache(mnemonicsConfigPath, cacheDir, ethWithdrawalAddress, true, logWriter(log))
+}
+
+func walkPubkeyCache(mnemonicsConfigPath string, cacheDir string, ethWithdrawalAddress common.Eth1Address, verify bool, log io.Writer) error {
+	mnemonics, err := LoadMnemonics(mnemonicsConfigPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", mnemonicsConfigPath, err)
+	}
+	var mismatches int64
+	for m, mnemonicSrc := range mnemonics {
+		seed, err := seedFromMnemonic(mnemonicSrc.Mnemonic, mnemonicSrc.Passphrase)
+		if err != nil {
+			return fmt.Errorf("mnemonic %d is bad", m)
+		}
+		prefix, _, err := mnemonicSrc.withdrawalPrefix(ethWithdrawalAddress)
+		if err != nil {
+			return fmt.Errorf("mnemonic %d: %w", m, err)
+		}
+		kinds := []int{signingKeyKind}
+		if prefix == common.BLS_WITHDRAWAL_PREFIX {
+			kinds = append(kinds, withdrawalKeyKind)
+		}
+		cache, err := openMnemonicPubkeyCache(cacheDir, seed)
+		if err != nil {
+			return err
+		}
+		var cachedCount, derivedCount int64
+		var g errgroup.Group
+		g.SetLimit(10_000)
+		for i := uint64(0); i < mnemonicSrc.Count; i++ {
+			idx := mnemonicSrc.Start + i
+			g.Go(func() error {
+				for _, kind := range kinds {
+					if !verify {
+						_, cached, err := cache.pubkey(seed, kind, idx)
+						if err != nil {
+							return err
+						}
+						if cached {
+							atomic.AddInt64(&cachedCount, 1)
+						} else {
+							atomic.AddInt64(&derivedCount, 1)
+						}
+						continue
+					}
+					cachedPub, ok, err := cache.get(kind, idx)
+					if err != nil || !ok {
+						return err
+					}
+					atomic.AddInt64(&cachedCount, 1)
+					_, pub, err := deriveKey(seed, keyPath(kind, idx))
+					if err != nil {
+						return err
+					}
+					if pub != cachedPub {
+						fmt.Fprintf(log, "mnemonic %d (%s): cached pubkey %s of %s does not match derived pubkey %s, removing it\n",
+							m, mnemonicSrc.TrancheName(m), cachedPub, keyPath(kind, idx), pub)
+						atomic.AddInt64(&mismatches, 1)
+						if err := cache.put(kind, idx, common.BLSPubkey{}); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+		}
+		err = g.Wait()
+		if closeErr := cache.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		if verify {
+			fmt.Fprintf(log, "mnemonic %d (%s): verified %d cached pubkeys\n", m, mnemonicSrc.TrancheName(m), cachedCount)
+		} else {
+			fmt.Fprintf(log, "mnemonic %d (%s): %d pubkeys were cached, derived %d pubkeys\n", m, mnemonicSrc.TrancheName(m), cachedCount, derivedCount)
+		}
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("found %d cached pubkeys that do not match the derived pubkeys", mismatches)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package genesis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestPubkeyCache(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	mnemonicsPath := filepath.Join(dir, "mnemonics.yaml")
+	writeTestFile(t, mnemonicsPath, []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 3
+`))
+	expected, err := GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, dir, common.Eth1Address{}, nil, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WarmPubkeyCache(mnemonicsPath, cacheDir, common.Eth1Address{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPubkeyCache(mnemonicsPath, cacheDir, common.Eth1Address{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cache is keyed by the seed, and only has pubkeys
+	seed, _ := seedFromMnemonic("test test test test test test test test test test test junk", "")
+	cache, err := openMnemonicPubkeyCache(cacheDir, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	for i := range expected {
+		pub, ok, err := cache.get(signingKeyKind, uint64(i))
+		if err != nil || !ok || pub != expected[i].Pubkey {
+			t.Fatalf("validator %d: unexpected cached pubkey %s (%v, %v)", i, pub, ok, err)
+		}
+	}
+	if _, ok, err := cache.get(signingKeyKind, 3); err != nil || ok {
+		t.Fatalf("expected cache miss of key index 3 (%v)", err)
+	}
+
+	// Builds with the cache give the same validators, also for new indices
+	writeTestFile(t, mnemonicsPath, []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 4
+`))
+	validators, err := GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, dir, common.Eth1Address{}, nil, cacheDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range expected {
+		if validators[i] != expected[i] {
+			t.Fatalf("validator %d: unexpected validator from cache", i)
+		}
+	}
+	if pub, ok, _ := cache.get(signingKeyKind, 3); !ok || pub != validators[3].Pubkey {
+		t.Fatal("expected new pubkey in cache")
+	}
+
+	// Entries that do not match their checksum are cache misses, and derived again
+	f := cache.files[signingKeyKind]
+	if _, err := f.WriteAt([]byte{0xff}, 2*pubkeyCacheEntrySize+10); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := cache.get(signingKeyKind, 2); err != nil || ok {
+		t.Fatalf("expected corrupted entry to be a cache miss (%v)", err)
+	}
+	validators, err = GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, dir, common.Eth1Address{}, nil, cacheDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validators[2] != expected[2] {
+		t.Fatal("expected corrupted entry to be derived again")
+	}
+	if pub, ok, _ := cache.get(signingKeyKind, 2); !ok || pub != expected[2].Pubkey {
+		t.Fatal("expected corrupted entry to be replaced")
+	}
+
+	// Corrupted pubkeys are detected, and removed from the cache
+	if err := cache.put(withdrawalKeyKind, 1, expected[0].Pubkey); err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyPubkeyCache(mnemonicsPath, cacheDir, common.Eth1Address{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "found 1 cached pubkeys that do not match") {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+	if _, ok, _ := cache.get(withdrawalKeyKind, 1); ok {
+		t.Fatal("expected mismatching pubkey to be removed")
+	}
+	if err := VerifyPubkeyCache(mnemonicsPath, cacheDir, common.Eth1Address{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected a single mnemonic dir in the cache: %v", err)
+	}
+}
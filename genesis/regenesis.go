@@ -0,0 +1,268 @@
+package genesis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// RegenesisSrc describes the existing beacon state to take the validator registry from, for a regenesis.
+type RegenesisSrc struct {
+	// StatePath is the SSZ beacon state, of any fork, with the same preset as the genesis spec.
+	StatePath string
+	// StateFork is the fork of the state. If empty, the fork is detected from the fork versions of the genesis spec,
+	// which only works if the state is of the same network.
+	StateFork string
+	// ReplaceIndices are the ranges of validators to replace with the validators of the genesis validator sources, in order.
+	// If empty, the first validators are replaced.
+	ReplaceIndices []IndexRange
+}
+
+// IndexRange is an inclusive range of validator indices.
+type IndexRange struct {
+	Start, End uint64
+}
+
+// Registry is the validator registry of an existing beacon state, with the epochs rebased to the new genesis.
+type Registry struct {
+	Validators []phase0.Validator
+	Balances   []common.Gwei
+	// Epoch is the epoch of the state the registry was loaded from.
+	Epoch common.Epoch
+}
+
+// validatorByteLength is the SSZ size of a validator record.
+const validatorByteLength = 48 + 32 + 8 + 1 + 4*8
+
+// LoadRegistry loads the validators and balances of an SSZ beacon state, of the given fork, or else of any fork of the spec.
+// Only the fields it needs are decoded, not the whole state, since the state of a large network is big.
+//
+// The epochs of the validators are rebased to the new genesis: the epochs before the epoch of the state become
+// the genesis epoch, and later epochs keep their distance to the epoch of the state. This keeps active validators active,
+// exited validators exited, and slashed and exiting validators on the way to become withdrawable.
+func LoadRegistry(spec *common.Spec, forkName string, stateData []byte) (*Registry, error) {
+	var fork *Fork
+	var err error
+	if forkName != "" {
+		fork, err = ForkByName(forkName)
+	} else {
+		fork, err = DetectFork(spec, stateData)
+	}
+	if err != nil {
+		return nil, err
+	}
+	typ := fork.StateType(spec)
+
+	// Find the fixed-size slot, and the offsets of the variable-size fields, in the fixed-size part of the state.
+	var slotPos uint64
+	var offsets []uint64
+	fieldOffset := make(map[string]int)
+	pos := uint64(0)
+	for _, f := range typ.Fields {
+		if f.Name == "slot" {
+			slotPos = pos
+		}
+		if f.Type.IsFixedByteLength() {
+			pos += f.Type.TypeByteLength()
+			continue
+		}
+		if pos+4 > uint64(len(stateData)) {
+			return nil, fmt.Errorf("state is too short for a %s state", fork.Name)
+		}
+		fieldOffset[f.Name] = len(offsets)
+		offsets = append(offsets, uint64(binary.LittleEndian.Uint32(stateData[pos:])))
+		pos += 4
+	}
+	if pos > uint64(len(stateData)) {
+		return nil, fmt.Errorf("state is too short for a %s state", fork.Name)
+	}
+	// fieldData returns the SSZ data of a variable-size field, which ends where the next variable-size field starts.
+	fieldData := func(name string) ([]byte, error) {
+		i := fieldOffset[name]
+		start, end := offsets[i], uint64(len(stateData))
+		if i+1 < len(offsets) {
+			end = offsets[i+1]
+		}
+		if start < pos || start > end || end > uint64(len(stateData)) {
+			return nil, fmt.Errorf("invalid %s offsets in %s state", name, fork.Name)
+		}
+		return stateData[start:end], nil
+	}
+
+	validatorsData, err := fieldData("validators")
+	if err != nil {
+		return nil, err
+	}
+	balancesData, err := fieldData("balances")
+	if err != nil {
+		return nil, err
+	}
+	if len(validatorsData)%validatorByteLength != 0 {
+		return nil, fmt.Errorf("validators size %d is not a multiple of %d", len(validatorsData), validatorByteLength)
+	}
+	count := len(validatorsData) / validatorByteLength
+	if len(balancesData) != count*8 {
+		return nil, fmt.Errorf("%d balances for %d validators", len(balancesData)/8, count)
+	}
+
+	slot := common.Slot(binary.LittleEndian.Uint64(stateData[slotPos:]))
+	epoch := spec.SlotToEpoch(slot)
+	rebase := func(e common.Epoch) common.Epoch {
+		if e == common.FAR_FUTURE_EPOCH {
+			return e
+		}
+		if e <= epoch {
+			return common.GENESIS_EPOCH
+		}
+		return common.GENESIS_EPOCH + e - epoch
+	}
+	reg := &Registry{
+		Validators: make([]phase0.Validator, count),
+		Balances:   make([]common.Gwei, count),
+		Epoch:      epoch,
+	}
+	for i := range reg.Validators {
+		d := validatorsData[i*validatorByteLength:]
+		v := &reg.Validators[i]
+		copy(v.Pubkey[:], d[0:48])
+		copy(v.WithdrawalCredentials[:], d[48:80])
+		v.EffectiveBalance = common.Gwei(binary.LittleEndian.Uint64(d[80:88]))
+		switch d[88] {
+		case 0:
+		case 1:
+			v.Slashed = true
+		default:
+			return nil, fmt.Errorf("validator %d has invalid slashed value %d", i, d[88])
+		}
+		v.ActivationEligibilityEpoch = rebase(common.Epoch(binary.LittleEndian.Uint64(d[89:97])))
+		v.ActivationEpoch = rebase(common.Epoch(binary.LittleEndian.Uint64(d[97:105])))
+		v.ExitEpoch = rebase(common.Epoch(binary.LittleEndian.Uint64(d[105:113])))
+		v.WithdrawableEpoch = rebase(common.Epoch(binary.LittleEndian.Uint64(d[113:121])))
+		reg.Balances[i] = common.Gwei(binary.LittleEndian.Uint64(balancesData[i*8:]))
+	}
+	return reg, nil
+}
+
+// LoadRegistryFile loads the validator registry of an SSZ beacon state file, see LoadRegistry.
+func LoadRegistryFile(spec *common.Spec, forkName string, path string) (*Registry, error) {
+	stateData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	reg, err := LoadRegistry(spec, forkName, stateData)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return reg, nil
+}
+
+// Replace the pubkeys and withdrawal credentials of the validators at the given indices with the given validators, in order.
+// If there are no ranges, the first validators are replaced. The balances and status of the replaced validators are kept,
+// but the effective balance is capped if the withdrawal credentials are not compounding anymore.
+// The new pubkeys must not be in the registry already.
+func (reg *Registry) Replace(spec *common.Spec, ranges []IndexRange, validators []phase0.KickstartValidatorData) error {
+	if len(ranges) == 0 && len(validators) > 0 {
+		ranges = []IndexRange{{Start: 0, End: uint64(len(validators)) - 1}}
+	}
+	// Check the ranges before expanding them, so a large range does not allocate an index for each validator in it.
+	var count uint64
+	for _, r := range ranges {
+		if r.End < r.Start {
+			return fmt.Errorf("invalid index range %d-%d: end before start", r.Start, r.End)
+		}
+		if r.End >= uint64(len(reg.Validators)) {
+			return fmt.Errorf("cannot replace validator %d, there are only %d validators", r.End, len(reg.Validators))
+		}
+		count += r.End - r.Start + 1
+	}
+	if count != uint64(len(validators)) {
+		return fmt.Errorf("%d validators to replace, but %d validators to replace them with", count, len(validators))
+	}
+	indices := make([]uint64, 0, count)
+	for _, r := range ranges {
+		for i := r.Start; i <= r.End; i++ {
+			indices = append(indices, i)
+		}
+	}
+	replacing := make(map[uint64]int, len(indices))
+	pubkeys := make(map[common.BLSPubkey]int, len(validators))
+	for i, index := range indices {
+		if _, ok := replacing[index]; ok {
+			return fmt.Errorf("validator %d is replaced twice", index)
+		}
+		replacing[index] = i
+		pubkeys[validators[i].Pubkey] = i
+	}
+	for index := range reg.Validators {
+		if _, ok := replacing[uint64(index)]; ok {
+			continue
+		}
+		if i, ok := pubkeys[reg.Validators[index].Pubkey]; ok {
+			return fmt.Errorf("replacement %d has the pubkey %s of validator %d, which is not replaced", i, validators[i].Pubkey, index)
+		}
+	}
+	for i, index := range indices {
+		v := &reg.Validators[index]
+		v.Pubkey = validators[i].Pubkey
+		v.WithdrawalCredentials = validators[i].WithdrawalCredentials
+		if v.WithdrawalCredentials[0] != COMPOUNDING_WITHDRAWAL_PREFIX && v.EffectiveBalance > spec.MAX_EFFECTIVE_BALANCE {
+			v.EffectiveBalance = spec.MAX_EFFECTIVE_BALANCE
+		}
+	}
+	return nil
+}
+
+// ParseIndices parses comma-separated validator indices and inclusive ranges of indices, like "0-99,200,300-310".
+// The ranges are not expanded: Registry.Replace checks them against the size of the registry first.
+func ParseIndices(s string) ([]IndexRange, error) {
+	var ranges []IndexRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.ParseUint(strings.TrimSpace(from), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q: %w", part, err)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.ParseUint(strings.TrimSpace(to), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index range %q: %w", part, err)
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid index range %q: end before start", part)
+			}
+		}
+		if end == math.MaxUint64 {
+			return nil, fmt.Errorf("invalid index %q: out of range", part)
+		}
+		ranges = append(ranges, IndexRange{Start: start, End: end})
+	}
+	return ranges, nil
+}
+
+// SetupRegenesisState creates a genesis state with the validator registry of an existing state. See SetupState.
+func SetupRegenesisState(spec *common.Spec, fork *Fork, eth1Time common.Timestamp,
+	eth1BlockHash common.Root, reg *Registry) (common.BeaconState, error) {
+
+	if !fork.AtLeast("electra") {
+		for i := range reg.Validators {
+			if reg.Validators[i].WithdrawalCredentials[0] == COMPOUNDING_WITHDRAWAL_PREFIX {
+				return nil, fmt.Errorf("validator %d has compounding withdrawal credentials, which are not supported before electra", i)
+			}
+		}
+	}
+	return setupState(spec, fork, eth1Time, eth1BlockHash, uint64(len(reg.Validators)),
+		func(i uint64) (phase0.Validator, common.Gwei) {
+			return reg.Validators[i], reg.Balances[i]
+		})
+}
@@ -0,0 +1,195 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+)
+
+func TestRegenesis(t *testing.T) {
+	spec := configs.Minimal
+	validators := testValidators(t, spec, 70)
+	res, err := Build(context.Background(), &Options{
+		Spec:       spec,
+		Fork:       "deneb",
+		Validators: validators[:64],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The source state is at epoch 10, with an exited validator, and a slashed validator that is exiting.
+	state := res.State
+	if err := state.SetSlot(10 * spec.SLOTS_PER_EPOCH); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exited, err := vals.Validator(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exited.SetExitEpoch(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := exited.SetWithdrawableEpoch(9); err != nil {
+		t.Fatal(err)
+	}
+	slashed, err := vals.Validator(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := slashed.MakeSlashed(); err != nil {
+		t.Fatal(err)
+	}
+	if err := slashed.SetExitEpoch(20); err != nil {
+		t.Fatal(err)
+	}
+	if err := slashed.SetWithdrawableEpoch(40); err != nil {
+		t.Fatal(err)
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bals.SetBalance(3, 31_000_000_000); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := state.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := LoadRegistry(spec, "", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reg.Epoch != 10 || len(reg.Validators) != 64 || reg.Balances[3] != 31_000_000_000 {
+		t.Fatalf("unexpected registry at epoch %d, with %d validators", reg.Epoch, len(reg.Validators))
+	}
+	for i, exp := range []phase0.Validator{
+		{Pubkey: validators[0].Pubkey, EffectiveBalance: spec.MAX_EFFECTIVE_BALANCE,
+			ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: common.FAR_FUTURE_EPOCH, WithdrawableEpoch: common.FAR_FUTURE_EPOCH},
+		{Pubkey: validators[1].Pubkey, EffectiveBalance: spec.MAX_EFFECTIVE_BALANCE,
+			ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: 0, WithdrawableEpoch: 0},
+		{Pubkey: validators[2].Pubkey, EffectiveBalance: spec.MAX_EFFECTIVE_BALANCE, Slashed: true,
+			ActivationEligibilityEpoch: 0, ActivationEpoch: 0, ExitEpoch: 10, WithdrawableEpoch: 30},
+	} {
+		if !reflect.DeepEqual(reg.Validators[i], exp) {
+			t.Errorf("validator %d: expected %+v, got %+v", i, exp, reg.Validators[i])
+		}
+	}
+
+	// Regenesis on a later fork, with validators 0 and 5 replaced
+	statePath := filepath.Join(t.TempDir(), "state.ssz")
+	if err := os.WriteFile(statePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	regen, err := Build(context.Background(), &Options{
+		Spec:       spec,
+		Fork:       "electra",
+		Validators: validators[64:66],
+		Regenesis: &RegenesisSrc{
+			StatePath:      statePath,
+			StateFork:      "deneb",
+			ReplaceIndices: []IndexRange{{0, 0}, {5, 5}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regen.ValidatorCount != 64 {
+		t.Fatalf("unexpected validator count %d", regen.ValidatorCount)
+	}
+	vals, err = regen.State.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, pub := range map[common.ValidatorIndex]common.BLSPubkey{
+		0: validators[64].Pubkey,
+		5: validators[65].Pubkey,
+		6: validators[6].Pubkey,
+	} {
+		v, err := vals.Validator(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := v.Pubkey(); err != nil || got != pub {
+			t.Fatalf("validator %d: unexpected pubkey %s", index, got)
+		}
+	}
+	bals, err = regen.State.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := bals.GetBalance(3); err != nil || b != 31_000_000_000 {
+		t.Fatalf("unexpected balance %d", b)
+	}
+	earliestExit, err := electraStateView(regen.State).EarliestExitEpoch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// exits continue after the exit of the slashed validator
+	if earliestExit != 11 {
+		t.Fatalf("unexpected earliest exit epoch %d", earliestExit)
+	}
+
+	// The replacements must not be in the registry already
+	if err := reg.Replace(spec, []IndexRange{{0, 0}}, validators[3:4]); err == nil {
+		t.Fatal("expected duplicate pubkey error")
+	}
+	if err := reg.Replace(spec, nil, validators[64:65]); err != nil {
+		t.Fatal(err)
+	}
+	if reg.Validators[0].Pubkey != validators[64].Pubkey {
+		t.Fatal("expected first validator to be replaced")
+	}
+}
+
+func TestParseIndices(t *testing.T) {
+	indices, err := ParseIndices("0-2, 7,10-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indices, []IndexRange{{0, 2}, {7, 7}, {10, 10}}) {
+		t.Fatalf("unexpected indices %v", indices)
+	}
+	for _, invalid := range []string{"a", "3-1", "1-x", "0-18446744073709551615", "18446744073709551615"} {
+		if _, err := ParseIndices(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestReplaceLargeRange(t *testing.T) {
+	spec := configs.Minimal
+	reg := &Registry{Validators: make([]phase0.Validator, 4), Balances: make([]common.Gwei, 4)}
+	validators := testValidators(t, spec, 2)
+	// not expanded before the check against the registry size
+	ranges, err := ParseIndices("0-18446744073709551614")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Replace(spec, ranges, validators); err == nil || !strings.Contains(err.Error(), "there are only 4 validators") {
+		t.Fatalf("expected range beyond the registry to be rejected, got %v", err)
+	}
+	if err := reg.Replace(spec, []IndexRange{{1, 3}}, validators); err == nil || !strings.Contains(err.Error(), "3 validators to replace") {
+		t.Fatalf("expected count mismatch, got %v", err)
+	}
+	if err := reg.Replace(spec, []IndexRange{{1, 1}, {3, 3}}, validators); err != nil {
+		t.Fatal(err)
+	}
+	if reg.Validators[1].Pubkey != validators[0].Pubkey || reg.Validators[3].Pubkey != validators[1].Pubkey {
+		t.Fatal("unexpected replaced validators")
+	}
+}
@@ -0,0 +1,262 @@
+package genesis
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sync/errgroup"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// backedList is a list view in the state, of which the backing can be replaced at once.
+// Setting the backing propagates the change to the state.
+type backedList interface {
+	SetBacking(b tree.Node) error
+}
+
+// altairRegistryState is a state with the per-validator lists added in Altair.
+type altairRegistryState interface {
+	PreviousEpochParticipation() (*altair.ParticipationRegistryView, error)
+	CurrentEpochParticipation() (*altair.ParticipationRegistryView, error)
+	InactivityScores() (*altair.InactivityScoresView, error)
+}
+
+// genesisValidator returns the validator record of a new genesis validator, with the activation processed.
+func genesisValidator(spec *common.Spec, electraOrLater bool, v *phase0.KickstartValidatorData) phase0.Validator {
+	maxEff := spec.MAX_EFFECTIVE_BALANCE
+	if electraOrLater && v.WithdrawalCredentials[0] == COMPOUNDING_WITHDRAWAL_PREFIX {
+		// Compounding validators may have up to MAX_EFFECTIVE_BALANCE_ELECTRA.
+		maxEff = spec.MAX_EFFECTIVE_BALANCE_ELECTRA
+	}
+	eff := v.Balance - v.Balance%spec.EFFECTIVE_BALANCE_INCREMENT
+	if eff > maxEff {
+		eff = maxEff
+	}
+	activate := eff == spec.MAX_EFFECTIVE_BALANCE
+	if electraOrLater {
+		// Electra activates any validator with at least the MIN_ACTIVATION_BALANCE at genesis.
+		activate = eff >= spec.MIN_ACTIVATION_BALANCE
+	}
+	val := phase0.Validator{
+		Pubkey:                     v.Pubkey,
+		WithdrawalCredentials:      v.WithdrawalCredentials,
+		EffectiveBalance:           eff,
+		ActivationEligibilityEpoch: common.FAR_FUTURE_EPOCH,
+		ActivationEpoch:            common.FAR_FUTURE_EPOCH,
+		ExitEpoch:                  common.FAR_FUTURE_EPOCH,
+		WithdrawableEpoch:          common.FAR_FUTURE_EPOCH,
+	}
+	if activate {
+		val.ActivationEligibilityEpoch = common.GENESIS_EPOCH
+		val.ActivationEpoch = common.GENESIS_EPOCH
+	}
+	return val
+}
+
+// SetRegistry sets the validators, balances, and (Altair and later) the participation and inactivity lists
+// of the count validators in the state, and returns the validator indices that are active at genesis.
+// The validator function returns the record and balance of each validator, and may be called more than once per validator,
+// concurrently. The state must not have any validators yet.
+//
+// Unlike adding the validators one by one, the trees of the lists are constructed at once, bottom-up.
+// The subtrees that most genesis validators have in common, like the epochs, effective balance and slashed status,
+// are shared between the validators, since the trees are immutable. This keeps the memory per validator small,
+// and hashing the shared subtrees is only done once.
+func SetRegistry(spec *common.Spec, state common.BeaconState, count uint64, validator func(i uint64) (phase0.Validator, common.Gwei)) ([]common.ValidatorIndex, error) {
+	if count > uint64(spec.VALIDATOR_REGISTRY_LIMIT) {
+		return nil, fmt.Errorf("too many validators: %d, limit is %d", count, spec.VALIDATOR_REGISTRY_LIMIT)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	// First pass: the activations, and the subtrees shared by the validators.
+	// The (effective_balance, slashed) subtrees, and the
+	// (activation_eligibility_epoch, activation_epoch, exit_epoch, withdrawable_epoch) subtrees.
+	// There are few different effective balances, and at genesis, few different epochs.
+	type balanceKey struct {
+		eff     common.Gwei
+		slashed bool
+	}
+	balanceNodes := make(map[balanceKey]tree.Node)
+	epochNodes := make(map[[4]common.Epoch]tree.Node)
+	var active []common.ValidatorIndex
+	for i := uint64(0); i < count; i++ {
+		v, _ := validator(i)
+		if v.ActivationEpoch <= common.GENESIS_EPOCH && common.GENESIS_EPOCH < v.ExitEpoch {
+			active = append(active, common.ValidatorIndex(i))
+		}
+		bk := balanceKey{v.EffectiveBalance, v.Slashed}
+		if _, ok := balanceNodes[bk]; !ok {
+			balanceNodes[bk] = tree.NewPairNode(view.Uint64View(v.EffectiveBalance).Backing(), view.BoolView(v.Slashed).Backing())
+		}
+		ek := [4]common.Epoch{v.ActivationEligibilityEpoch, v.ActivationEpoch, v.ExitEpoch, v.WithdrawableEpoch}
+		if _, ok := epochNodes[ek]; !ok {
+			epochNodes[ek] = tree.NewPairNode(
+				tree.NewPairNode(view.Uint64View(ek[0]).Backing(), view.Uint64View(ek[1]).Backing()),
+				tree.NewPairNode(view.Uint64View(ek[2]).Backing(), view.Uint64View(ek[3]).Backing()),
+			)
+		}
+	}
+
+	// Second pass: the validator records, in parallel.
+	validatorNodes := make([]tree.Node, count)
+	var g errgroup.Group
+	workers := uint64(runtime.NumCPU())
+	for w := uint64(0); w < workers; w++ {
+		g.Go(func() error {
+			for i := w; i < count; i += workers {
+				v, _ := validator(i)
+				var pubA, pubB tree.Root
+				copy(pubA[:], v.Pubkey[:32])
+				copy(pubB[:], v.Pubkey[32:])
+				creds := tree.Root(v.WithdrawalCredentials)
+				validatorNodes[i] = tree.NewPairNode(
+					tree.NewPairNode(
+						tree.NewPairNode(tree.NewPairNode(&pubA, &pubB), &creds),
+						balanceNodes[balanceKey{v.EffectiveBalance, v.Slashed}],
+					),
+					epochNodes[[4]common.Epoch{v.ActivationEligibilityEpoch, v.ActivationEpoch, v.ExitEpoch, v.WithdrawableEpoch}],
+				)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	if n, err := vals.ValidatorCount(); err != nil {
+		return nil, err
+	} else if n != 0 {
+		return nil, fmt.Errorf("expected no validators in state, got %d", n)
+	}
+	if err := setListBacking(vals, phase0.ValidatorsRegistryType(spec).Limit(), validatorNodes, count); err != nil {
+		return nil, fmt.Errorf("failed to set validators: %w", err)
+	}
+
+	// The balances are packed 4 per chunk. Chunks of equal balances are shared, like the chunks of the many validators with 32 ETH.
+	balanceChunks := make([]tree.Node, (count+3)/4)
+	var prev *tree.Root
+	for c := range balanceChunks {
+		var chunk tree.Root
+		for j := uint64(0); j < 4 && uint64(c)*4+j < count; j++ {
+			_, balance := validator(uint64(c)*4 + j)
+			binary.LittleEndian.PutUint64(chunk[j*8:], uint64(balance))
+		}
+		if prev != nil && *prev == chunk {
+			balanceChunks[c] = prev
+		} else {
+			prev = &chunk
+			balanceChunks[c] = prev
+		}
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		return nil, err
+	}
+	if err := setListBacking(bals, phase0.RegistryBalancesType(spec).BottomNodeLimit(), balanceChunks, count); err != nil {
+		return nil, fmt.Errorf("failed to set balances: %w", err)
+	}
+
+	if st, ok := state.(altairRegistryState); ok {
+		// New in Altair: the participation flags and inactivity scores start at zero.
+		prevPart, err := st.PreviousEpochParticipation()
+		if err != nil {
+			return nil, err
+		}
+		currPart, err := st.CurrentEpochParticipation()
+		if err != nil {
+			return nil, err
+		}
+		scores, err := st.InactivityScores()
+		if err != nil {
+			return nil, err
+		}
+		for _, zeroList := range []struct {
+			list backedList
+			typ  *view.BasicListTypeDef
+		}{
+			{prevPart, altair.ParticipationRegistryType(spec)},
+			{currPart, altair.ParticipationRegistryType(spec)},
+			{scores, altair.InactivityScoresType(spec)},
+		} {
+			depth := tree.CoverDepth(zeroList.typ.BottomNodeLimit())
+			perChunk := zeroList.typ.ElementsPerBottomNode()
+			contents := zeroFilledSubtree((count+perChunk-1)/perChunk, depth)
+			if err := zeroList.list.SetBacking(tree.NewPairNode(contents, view.Uint64View(count).Backing())); err != nil {
+				return nil, fmt.Errorf("failed to set participation and inactivity scores: %w", err)
+			}
+		}
+	}
+	return active, nil
+}
+
+// setListBacking sets the backing of a list with the given bottom nodes, and the list length.
+func setListBacking(list any, bottomNodeLimit uint64, nodes []tree.Node, length uint64) error {
+	bl, ok := list.(backedList)
+	if !ok {
+		return fmt.Errorf("unexpected list type %T", list)
+	}
+	contents, err := tree.SubtreeFillToContents(nodes, tree.CoverDepth(bottomNodeLimit))
+	if err != nil {
+		return err
+	}
+	return bl.SetBacking(tree.NewPairNode(contents, view.Uint64View(length).Backing()))
+}
+
+// zeroFilledSubtree returns a subtree with count zero chunks, like SubtreeFillToContents would,
+// but with a single node for each full zero subtree, instead of a node per chunk.
+func zeroFilledSubtree(count uint64, depth uint8) tree.Node {
+	full := make([]tree.Node, depth+1)
+	full[0] = &tree.ZeroHashes[0]
+	for d := uint8(1); d <= depth; d++ {
+		full[d] = tree.NewPairNode(full[d-1], full[d-1])
+	}
+	var fill func(count uint64, depth uint8) tree.Node
+	fill = func(count uint64, depth uint8) tree.Node {
+		if count == 0 {
+			return &tree.ZeroHashes[depth]
+		}
+		if count == uint64(1)<<depth {
+			return full[depth]
+		}
+		pivot := uint64(1) << (depth - 1)
+		if count <= pivot {
+			return tree.NewPairNode(fill(count, depth-1), &tree.ZeroHashes[depth-1])
+		}
+		return tree.NewPairNode(full[depth-1], fill(count-pivot, depth-1))
+	}
+	return fill(count, depth)
+}
+
+// genesisSyncCommittee returns the sync committee of the validator indices, without loading all pubkeys of the state.
+func genesisSyncCommittee(indices []common.ValidatorIndex, pubkey func(i uint64) common.BLSPubkey) (*common.SyncCommittee, error) {
+	pubs := make([]common.BLSPubkey, len(indices))
+	blsPubs := make([]*blsu.Pubkey, len(indices))
+	for i, idx := range indices {
+		pubs[i] = pubkey(uint64(idx))
+		blsPubs[i] = new(blsu.Pubkey)
+		if err := blsPubs[i].Deserialize((*[48]byte)(&pubs[i])); err != nil {
+			return nil, fmt.Errorf("invalid pubkey of sync committee member %d: %w", idx, err)
+		}
+	}
+	aggregate, err := blsu.AggregatePubkeys(blsPubs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate sync-committee bls pubkeys: %w", err)
+	}
+	return &common.SyncCommittee{
+		Pubkeys:         pubs,
+		AggregatePubkey: aggregate.Serialize(),
+	}, nil
+}
@@ -0,0 +1,160 @@
+package genesis
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// addValidators adds the validators one by one, with the activations processed, as reference for SetRegistry.
+func addValidators(t *testing.T, spec *common.Spec, state common.BeaconState, validators []phase0.KickstartValidatorData, electraOrLater bool) {
+	for i, v := range validators {
+		if err := state.AddValidator(spec, v.Pubkey, v.WithdrawalCredentials, v.Balance); err != nil {
+			t.Fatal(err)
+		}
+		vals, err := state.Validators()
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, err := vals.Validator(common.ValidatorIndex(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := genesisValidator(spec, electraOrLater, &validators[i])
+		if err := val.SetEffectiveBalance(expected.EffectiveBalance); err != nil {
+			t.Fatal(err)
+		}
+		if expected.ActivationEpoch == common.GENESIS_EPOCH {
+			if err := val.SetActivationEligibilityEpoch(common.GENESIS_EPOCH); err != nil {
+				t.Fatal(err)
+			}
+			if err := val.SetActivationEpoch(common.GENESIS_EPOCH); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSetRegistry(t *testing.T) {
+	spec := configs.Minimal
+	validators := testValidators(t, spec, 37)
+	validators[3].Balance = 16_000_000_000
+	validators[4].Balance = 33_500_000_000
+	validators[5].WithdrawalCredentials = common.Root{0x01, 12: 0xde, 31: 0xad}
+	for _, fork := range Forks {
+		t.Run(fork.Name, func(t *testing.T) {
+			vals := append([]phase0.KickstartValidatorData{}, validators...)
+			electraOrLater := fork.AtLeast("electra")
+			if electraOrLater {
+				vals[6].WithdrawalCredentials[0] = COMPOUNDING_WITHDRAWAL_PREFIX
+				vals[6].Balance = 100_000_000_000
+			}
+			expected := fork.NewState(spec)
+			addValidators(t, spec, expected, vals, electraOrLater)
+			state := fork.NewState(spec)
+			active, err := SetRegistry(spec, state, uint64(len(vals)), func(i uint64) (phase0.Validator, common.Gwei) {
+				return genesisValidator(spec, electraOrLater, &vals[i]), vals[i].Balance
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(active) != len(vals)-1 || active[3] != 4 {
+				t.Fatalf("unexpected active validators: %v", active)
+			}
+			hFn := tree.GetHashFn()
+			if a, b := expected.HashTreeRoot(hFn), state.HashTreeRoot(hFn); a != b {
+				t.Fatalf("state root mismatch: expected %s, got %s", a, b)
+			}
+
+			// The state can be modified further, like a state with validators added one by one.
+			if err := state.AddValidator(spec, validators[0].Pubkey, validators[0].WithdrawalCredentials, validators[0].Balance); err != nil {
+				t.Fatal(err)
+			}
+			if err := expected.AddValidator(spec, validators[0].Pubkey, validators[0].WithdrawalCredentials, validators[0].Balance); err != nil {
+				t.Fatal(err)
+			}
+			if a, b := expected.HashTreeRoot(hFn), state.HashTreeRoot(hFn); a != b {
+				t.Fatalf("state root mismatch after adding a validator: expected %s, got %s", a, b)
+			}
+		})
+	}
+}
+
+// BenchmarkBuild measures the time and the peak memory to build an electra genesis state with interop validators,
+// including the key generation, at a small size and at the 2M validators of the 2 GiB peak memory target.
+// The peak is the total memory mapped by the Go runtime, sampled during the build, which is an upper bound of the RSS.
+// The 2M run fails if the peak is above the target.
+func BenchmarkBuild(b *testing.B) {
+	const peakMemoryTarget = 2 << 30
+	for _, size := range []struct {
+		name   string
+		count  uint64
+		target uint64
+	}{
+		{"100k", 100_000, 0},
+		{"2M", 2_000_000, peakMemoryTarget},
+	} {
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				debug.FreeOSMemory()
+				peak := samplePeakMemory()
+				res, err := Build(context.Background(), &Options{
+					Spec:              configs.Mainnet,
+					Fork:              "electra",
+					InteropValidators: size.count,
+					TranchesDir:       b.TempDir(),
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				p := peak()
+				b.ReportMetric(float64(p)/(1<<20), "peak-MiB")
+				if size.target != 0 && p > size.target {
+					b.Fatalf("peak memory %d MiB is above the target of %d MiB", p>>20, size.target>>20)
+				}
+				runtime.KeepAlive(res)
+			}
+		})
+	}
+}
+
+// samplePeakMemory samples the memory mapped by the Go runtime until the returned function is called,
+// which returns the peak.
+func samplePeakMemory() func() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+	read := func() uint64 {
+		metrics.Read(sample)
+		return sample[0].Value.Uint64()
+	}
+	var peak uint64
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			peak = max(peak, read())
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() uint64 {
+		close(done)
+		<-stopped
+		return max(peak, read())
+	}
+}
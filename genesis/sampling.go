@@ -0,0 +1,153 @@
+package genesis
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+
+	"github.com/protolambda/eth2-testnet-genesis/fulu"
+)
+
+// computeSyncCommitteeIndices returns the sync committee indices of the epoch, like get_next_sync_committee_indices of the fork.
+// From Electra on, the candidates are sampled by 16-bit random values against MAX_EFFECTIVE_BALANCE_ELECTRA,
+// so compounding validators with a higher effective balance are more likely to be in the committee.
+func computeSyncCommitteeIndices(spec *common.Spec, fork *Fork, state common.BeaconState, epoch common.Epoch, active []common.ValidatorIndex) ([]common.ValidatorIndex, error) {
+	if !fork.AtLeast("electra") {
+		return common.ComputeSyncCommitteeIndices(spec, state, epoch, active)
+	}
+	if len(active) == 0 {
+		return nil, errors.New("no active validators to compute sync committee from")
+	}
+	mixes, err := state.RandaoMixes()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	seed, err := common.GetSeed(spec, mixes, epoch, common.DOMAIN_SYNC_COMMITTEE)
+	if err != nil {
+		return nil, err
+	}
+	// The committee can contain duplicate indices for small validator sets.
+	indices := make([]common.ValidatorIndex, 0, spec.SYNC_COMMITTEE_SIZE)
+	err = sampleElectra(spec, vals, active, seed, func(index common.ValidatorIndex) bool {
+		indices = append(indices, index)
+		return uint64(len(indices)) < uint64(spec.SYNC_COMMITTEE_SIZE)
+	})
+	return indices, err
+}
+
+// computeProposerIndex is the Electra version of compute_proposer_index.
+func computeProposerIndex(spec *common.Spec, registry common.ValidatorRegistry, active []common.ValidatorIndex, seed common.Root) (common.ValidatorIndex, error) {
+	if len(active) == 0 {
+		return 0, errors.New("no active validators available to compute proposer")
+	}
+	var proposer common.ValidatorIndex
+	err := sampleElectra(spec, registry, active, seed, func(index common.ValidatorIndex) bool {
+		proposer = index
+		return false
+	})
+	return proposer, err
+}
+
+// sampleElectra goes through the active validators in shuffled order, like the Electra compute_proposer_index
+// and get_next_sync_committee_indices, and accepts each candidate with a 16-bit random value,
+// weighed by its effective balance against MAX_EFFECTIVE_BALANCE_ELECTRA. It stops when accept returns false.
+func sampleElectra(spec *common.Spec, registry common.ValidatorRegistry, active []common.ValidatorIndex, seed common.Root, accept func(index common.ValidatorIndex) bool) error {
+	const maxRandomValue = 1<<16 - 1
+	total := uint64(len(active))
+
+	var buf [32 + 8]byte
+	copy(buf[0:32], seed[:])
+	hFn := hashing.GetHashFn()
+	var randomBytes common.Root
+	for i := uint64(0); ; i++ {
+		if i%16 == 0 {
+			binary.LittleEndian.PutUint64(buf[32:], i/16)
+			randomBytes = hFn(buf[:])
+		}
+		shuffledI := common.PermuteIndex(uint8(spec.SHUFFLE_ROUND_COUNT), common.ValidatorIndex(i%total), total, seed)
+		candidateIndex := active[shuffledI]
+		offset := (i % 16) * 2
+		randomValue := uint64(binary.LittleEndian.Uint16(randomBytes[offset : offset+2]))
+		validator, err := registry.Validator(candidateIndex)
+		if err != nil {
+			return err
+		}
+		effectiveBalance, err := validator.EffectiveBalance()
+		if err != nil {
+			return err
+		}
+		if uint64(effectiveBalance)*maxRandomValue >= uint64(spec.MAX_EFFECTIVE_BALANCE_ELECTRA)*randomValue {
+			if !accept(candidateIndex) {
+				return nil
+			}
+		}
+	}
+}
+
+// computeProposerIndices computes the proposer of each slot in the given epoch, like get_beacon_proposer_indices.
+func computeProposerIndices(spec *common.Spec, state common.BeaconState, epoch common.Epoch, active []common.ValidatorIndex) ([]common.ValidatorIndex, error) {
+	mixes, err := state.RandaoMixes()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	startSlot, err := spec.EpochStartSlot(epoch)
+	if err != nil {
+		return nil, err
+	}
+	epochSeed, err := common.GetSeed(spec, mixes, epoch, common.DOMAIN_BEACON_PROPOSER)
+	if err != nil {
+		return nil, err
+	}
+	hFn := hashing.GetHashFn()
+	var buf [32 + 8]byte
+	copy(buf[0:32], epochSeed[:])
+	proposers := make([]common.ValidatorIndex, spec.SLOTS_PER_EPOCH)
+	for i := common.Slot(0); i < spec.SLOTS_PER_EPOCH; i++ {
+		binary.LittleEndian.PutUint64(buf[32:], uint64(startSlot+i))
+		proposer, err := computeProposerIndex(spec, vals, active, hFn(buf[:]))
+		if err != nil {
+			return nil, err
+		}
+		proposers[i] = proposer
+	}
+	return proposers, nil
+}
+
+// initializeProposerLookahead fills the proposer lookahead of the Fulu state,
+// for the current epoch and the MIN_SEED_LOOKAHEAD epochs after it, like initialize_proposer_lookahead.
+func initializeProposerLookahead(spec *common.Spec, state *fulu.BeaconStateView) error {
+	slot, err := state.Slot()
+	if err != nil {
+		return err
+	}
+	currentEpoch := spec.SlotToEpoch(slot)
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	indicesBounded, err := common.LoadBoundedIndices(vals)
+	if err != nil {
+		return err
+	}
+	lookahead := make([]common.ValidatorIndex, 0, fulu.ProposerLookaheadLength(spec))
+	for i := common.Epoch(0); i <= spec.MIN_SEED_LOOKAHEAD; i++ {
+		epoch := currentEpoch + i
+		active := common.ActiveIndices(indicesBounded, epoch)
+		proposers, err := computeProposerIndices(spec, state, epoch, active)
+		if err != nil {
+			return err
+		}
+		lookahead = append(lookahead, proposers...)
+	}
+	return state.SetProposerLookahead(lookahead)
+}
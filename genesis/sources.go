@@ -0,0 +1,241 @@
+package genesis
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// ValidatorSources are the sources of the genesis validators, loaded in this order. All sources are optional.
+type ValidatorSources struct {
+	// Log receives the progress of loading the validators, as lines of text. Discarded if nil.
+	Log io.Writer
+	// InteropValidators is the number of validators with insecure interop keys, see InteropSecretKey.
+	// These come first, so the validator index matches the interop key index.
+	InteropValidators uint64
+	// MnemonicsConfigPath is a YAML file with mnemonics to derive validators from, see MnemonicSrc.
+	MnemonicsConfigPath string
+	// TranchesDir is the directory to write the pubkeys of each mnemonic tranche, and of the interop validators, to.
+	TranchesDir string
+	// EthWithdrawalAddress is the withdrawal address of the interop validators, and the default of the mnemonic validators.
+	EthWithdrawalAddress common.Eth1Address
+	// KeystoresExport optionally writes the keystores of the mnemonic validators.
+	KeystoresExport *KeystoresExport
+	// PubkeyCacheDir optionally caches the pubkeys derived from the mnemonics, see WarmPubkeyCache.
+	PubkeyCacheDir string
+	// ValidatorsListPath is a file with a validator per line, see LoadValidatorsFromFile.
+	ValidatorsListPath string
+	// Keystores is a directory of EIP-2335 keystores.
+	Keystores *KeystoresSrc
+	// DepositDataPaths are deposit_data JSON files, see LoadDepositData.
+	DepositDataPaths []string
+	// Fork is the fork of the genesis state, to check the withdrawal credentials of the deposits against. Not checked if nil.
+	Fork *Fork
+	// Validators are added after the validators of all other sources.
+	Validators []phase0.KickstartValidatorData
+	// Strict makes rejected deposits fatal, instead of skipping them.
+	Strict bool
+}
+
+// ValidatorOrigins describes where each validator was loaded from, for error reports.
+// The origins are kept per source, as contiguous ranges of validators, not per validator.
+type ValidatorOrigins struct {
+	ranges []originRange
+	count  int
+}
+
+type originRange struct {
+	start  int
+	origin func(i int) string
+}
+
+// Add the origin of the next count validators. The origin function gets the index within the added validators.
+func (o *ValidatorOrigins) Add(count int, origin func(i int) string) {
+	o.ranges = append(o.ranges, originRange{start: o.count, origin: origin})
+	o.count += count
+}
+
+// Origin describes the source (and line or entry in the source) of the i-th validator.
+func (o *ValidatorOrigins) Origin(i int) string {
+	r := sort.Search(len(o.ranges), func(j int) bool { return o.ranges[j].start > i }) - 1
+	if r < 0 || i >= o.count {
+		return fmt.Sprintf("unknown source of validator %d", i)
+	}
+	return o.ranges[r].origin(i - o.ranges[r].start)
+}
+
+// LoadValidatorKeys loads the validators of all sources. A source that fails to load is fatal:
+// the problems of all sources are reported at once, with the source and line of each problem.
+// The rejected deposits are returned if not Strict.
+//
+// The validators of each source are joined into one slice at the end, with a single copy, or no copy if there is only one source:
+// at millions of validators, growing a slice source by source would hold multiple copies of the validators at once.
+func LoadValidatorKeys(spec *common.Spec, src *ValidatorSources) ([]phase0.KickstartValidatorData, *ValidatorOrigins, []*DepositDataProblem, error) {
+	log := logWriter(src.Log)
+	var parts [][]phase0.KickstartValidatorData
+	origins := new(ValidatorOrigins)
+	var rejectedDeposits []*DepositDataProblem
+	var problems []error
+
+	if src.InteropValidators > 0 {
+		val, err := GenerateInteropValidators(spec, src.InteropValidators, src.TranchesDir, src.EthWithdrawalAddress)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("interop validators: %w", err))
+		} else {
+			fmt.Fprintf(log, "generated %d interop validators\n", len(val))
+			parts = append(parts, val)
+			origins.Add(len(val), func(i int) string { return fmt.Sprintf("interop key index %d", i) })
+		}
+	}
+
+	if src.MnemonicsConfigPath != "" {
+		val, mnemonics, err := generateValidatorKeysByMnemonic(spec, src.MnemonicsConfigPath, src.TranchesDir, src.EthWithdrawalAddress, src.KeystoresExport, src.PubkeyCacheDir, log)
+		if err != nil {
+			problems = append(problems, flattenErrors(err)...)
+		} else {
+			fmt.Fprintf(log, "generated %d validators from mnemonic yaml (%s)\n", len(val), src.MnemonicsConfigPath)
+			parts = append(parts, val)
+			for m := range mnemonics {
+				mnemonicSrc := &mnemonics[m]
+				name := mnemonicSrc.TrancheName(m)
+				origins.Add(int(mnemonicSrc.Count), func(i int) string {
+					return fmt.Sprintf("%s line %d (%s), key index %d", src.MnemonicsConfigPath, mnemonicSrc.line, name, mnemonicSrc.Start+uint64(i))
+				})
+			}
+		}
+	}
+
+	if src.ValidatorsListPath != "" {
+		val, lines, err := loadValidatorsFromFile(spec, src.ValidatorsListPath)
+		if err != nil {
+			problems = append(problems, flattenErrors(err)...)
+		} else {
+			fmt.Fprintf(log, "loaded %d validators from validators list (%s)\n", len(val), src.ValidatorsListPath)
+			parts = append(parts, val)
+			origins.Add(len(val), func(i int) string {
+				return fmt.Sprintf("%s line %d", src.ValidatorsListPath, lines[i])
+			})
+		}
+	}
+
+	if src.Keystores != nil && src.Keystores.Dir != "" {
+		val, paths, err := loadValidatorsFromKeystores(spec, src.Keystores, log)
+		if err != nil {
+			problems = append(problems, flattenErrors(err)...)
+		} else {
+			fmt.Fprintf(log, "loaded %d validators from keystores (%s)\n", len(val), src.Keystores.Dir)
+			parts = append(parts, val)
+			origins.Add(len(val), func(i int) string { return paths[i] })
+		}
+	}
+
+	if len(src.DepositDataPaths) > 0 {
+		val, entries, rejected, err := loadDepositData(spec, src.Fork, src.DepositDataPaths)
+		if err != nil {
+			problems = append(problems, err)
+		} else {
+			for _, p := range rejected {
+				if src.Strict {
+					problems = append(problems, p)
+				} else {
+					rejectedDeposits = append(rejectedDeposits, p)
+				}
+			}
+			fmt.Fprintf(log, "loaded %d validators from deposit data, rejected %d deposits\n", len(val), len(rejected))
+			parts = append(parts, val)
+			origins.Add(len(val), func(i int) string { return entries[i] })
+		}
+	}
+
+	if len(src.Validators) > 0 {
+		parts = append(parts, src.Validators)
+		origins.Add(len(src.Validators), func(i int) string { return fmt.Sprintf("validator %d of the given validators", i) })
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, nil, fmt.Errorf("found %d problems in the validator sources:\n%w", len(problems), errors.Join(problems...))
+	}
+	return joinValidators(parts), origins, rejectedDeposits, nil
+}
+
+// joinValidators concatenates the validators of the sources, without a copy if there is a single source.
+func joinValidators(parts [][]phase0.KickstartValidatorData) []phase0.KickstartValidatorData {
+	switch len(parts) {
+	case 0:
+		return []phase0.KickstartValidatorData{}
+	case 1:
+		return parts[0]
+	}
+	total := 0
+	for _, part := range parts {
+		total += len(part)
+	}
+	validators := make([]phase0.KickstartValidatorData, 0, total)
+	for i, part := range parts {
+		validators = append(validators, part...)
+		// release each part as soon as it is copied
+		parts[i] = nil
+	}
+	return validators
+}
+
+// CheckValidators checks that no pubkey is used twice, also across sources, and that every pubkey is a valid BLS point.
+// All problems are reported at once, with the origin of the validators.
+func CheckValidators(validators []phase0.KickstartValidatorData, origins *ValidatorOrigins) error {
+	var problems []error
+	seen := make(map[common.BLSPubkey]int, len(validators))
+	for i := range validators {
+		if j, ok := seen[validators[i].Pubkey]; ok {
+			problems = append(problems, fmt.Errorf("%s: duplicate pubkey %s, also in %s",
+				origins.Origin(i), validators[i].Pubkey, origins.Origin(j)))
+			continue
+		}
+		seen[validators[i].Pubkey] = i
+	}
+
+	var invalid []int
+	var mu sync.Mutex
+	var g errgroup.Group
+	workers := runtime.NumCPU()
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for i := w; i < len(validators); i += workers {
+				var pub blsu.Pubkey
+				if err := pub.Deserialize((*[48]byte)(&validators[i].Pubkey)); err != nil {
+					mu.Lock()
+					invalid = append(invalid, i)
+					mu.Unlock()
+				}
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	sort.Ints(invalid)
+	for _, i := range invalid {
+		problems = append(problems, fmt.Errorf("%s: pubkey %s is not a valid BLS point", origins.Origin(i), validators[i].Pubkey))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problems in the validators:\n%w", len(problems), errors.Join(problems...))
+	}
+	return nil
+}
+
+// flattenErrors splits joined errors, to report each problem on its own.
+func flattenErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
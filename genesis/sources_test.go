@@ -0,0 +1,103 @@
+package genesis
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestLoadValidatorKeysProblems(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	mnemonicsPath := filepath.Join(dir, "mnemonics.yaml")
+	writeTestFile(t, mnemonicsPath, []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 1
+- mnemonic: "test test test test test test test test test test test test"
+  count: 1
+`))
+	validatorsPath := filepath.Join(dir, "validators.txt")
+	writeTestFile(t, validatorsPath, []byte(`# comment
+0xa572cbea904d67468808c8eb50a9450c9721db309128012543902d0ac358a62ae28f75bb8f1c7c42c39a8c5529bf0f4e:001547805ff0547da9e51a7463a6a0c603eeda01dd930f7016185f0642b9ecaf
+0xa572cbea904d67468808c8eb50a9450c9721db309128012543902d0ac358a62ae28f75bb8f1c7c42c39a8c5529bf0f4e
+0xa572cbea:001547805ff0547da9e51a7463a6a0c603eeda01dd930f7016185f0642b9ecaf
+`))
+
+	_, _, _, err := LoadValidatorKeys(spec, &ValidatorSources{
+		MnemonicsConfigPath: mnemonicsPath,
+		TranchesDir:         dir,
+		ValidatorsListPath:  validatorsPath,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, expected := range []string{
+		"found 3 problems",
+		mnemonicsPath + " line 4 (mnemonic 1): mnemonic is not valid",
+		validatorsPath + " line 3: expected <pubkey>:<withdrawal credentials>[:<balance>]",
+		validatorsPath + " line 4: invalid pubkey (invalid length)",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in error:\n%s", expected, err)
+		}
+	}
+}
+
+func TestCheckValidators(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	mnemonicsPath := filepath.Join(dir, "mnemonics.yaml")
+	writeTestFile(t, mnemonicsPath, []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  name: ops
+  count: 2
+`))
+	generated, err := GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, dir, common.Eth1Address{}, nil, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a validator of the mnemonic again, and a pubkey that is not on the curve
+	validatorsPath := filepath.Join(dir, "validators.txt")
+	writeTestFile(t, validatorsPath, []byte(fmt.Sprintf("%s:%s\n\n%s:%s\n",
+		generated[1].Pubkey, generated[1].WithdrawalCredentials.String()[2:],
+		"0x"+strings.Repeat("ab", 48), generated[0].WithdrawalCredentials.String()[2:])))
+
+	validators, origins, _, err := LoadValidatorKeys(spec, &ValidatorSources{
+		MnemonicsConfigPath: mnemonicsPath,
+		TranchesDir:         dir,
+		ValidatorsListPath:  validatorsPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 4 {
+		t.Fatalf("expected 4 validators, got %d", len(validators))
+	}
+	if o := origins.Origin(1); o != mnemonicsPath+" line 2 (ops), key index 1" {
+		t.Fatalf("unexpected origin: %s", o)
+	}
+	if o := origins.Origin(3); o != validatorsPath+" line 3" {
+		t.Fatalf("unexpected origin: %s", o)
+	}
+
+	if err := CheckValidators(validators[:2], origins); err != nil {
+		t.Fatal(err)
+	}
+	err = CheckValidators(validators, origins)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, expected := range []string{
+		"found 2 problems",
+		validatorsPath + " line 1: duplicate pubkey " + generated[1].Pubkey.String() + ", also in " + mnemonicsPath + " line 2 (ops), key index 1",
+		validatorsPath + " line 3: pubkey 0xabab",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in error:\n%s", expected, err)
+		}
+	}
+}
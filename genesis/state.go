@@ -0,0 +1,109 @@
+package genesis
+
+import (
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+)
+
+func SetupState(spec *common.Spec, fork *Fork, eth1Time common.Timestamp,
+	eth1BlockHash common.Root, validators []phase0.KickstartValidatorData) (common.BeaconState, error) {
+
+	electraOrLater := fork.AtLeast("electra")
+	for i := range validators {
+		if validators[i].WithdrawalCredentials[0] == COMPOUNDING_WITHDRAWAL_PREFIX && !electraOrLater {
+			return nil, fmt.Errorf("validator %d has compounding withdrawal credentials, which are not supported before electra", i)
+		}
+	}
+	return setupState(spec, fork, eth1Time, eth1BlockHash, uint64(len(validators)),
+		func(i uint64) (phase0.Validator, common.Gwei) {
+			return genesisValidator(spec, electraOrLater, &validators[i]), validators[i].Balance
+		})
+}
+
+// setupState creates a genesis state with count validators. See SetRegistry for the validator function.
+func setupState(spec *common.Spec, fork *Fork, eth1Time common.Timestamp,
+	eth1BlockHash common.Root, count uint64, validator func(i uint64) (phase0.Validator, common.Gwei)) (common.BeaconState, error) {
+
+	state := fork.NewState(spec)
+	if err := state.SetGenesisTime(eth1Time + spec.GENESIS_DELAY); err != nil {
+		return nil, err
+	}
+	previousForkVersion, forkVersion := fork.Versions(spec)
+	if err := state.SetFork(common.Fork{
+		PreviousVersion: previousForkVersion,
+		CurrentVersion:  forkVersion,
+		Epoch:           common.GENESIS_EPOCH,
+	}); err != nil {
+		return nil, err
+	}
+	// Empty deposit-tree. Build continues from the deposits of a live deposit contract with SetDepositContractState.
+	eth1Dat := common.Eth1Data{
+		DepositRoot:  phase0.NewDepositRootsView().HashTreeRoot(tree.GetHashFn()),
+		DepositCount: 0,
+		BlockHash:    eth1BlockHash,
+	}
+	if err := state.SetEth1Data(eth1Dat); err != nil {
+		return nil, err
+	}
+	// Leave the deposit index to 0. No deposits happened.
+	if i, err := state.Eth1DepositIndex(); err != nil {
+		return nil, err
+	} else if i != 0 {
+		return nil, fmt.Errorf("expected 0 deposit index in state, got %d", i)
+	}
+	emptyBody := fork.BlockBodyType(spec).New()
+	// Setting to a valid BeaconBlockBody HTR has become official test setup behavior in Deneb,
+	// see initialize_beacon_state_from_eth1.
+	latestHeader := &common.BeaconBlockHeader{
+		BodyRoot: emptyBody.HashTreeRoot(tree.GetHashFn()),
+	}
+	if err := state.SetLatestBlockHeader(latestHeader); err != nil {
+		return nil, err
+	}
+	// Seed RANDAO with Eth1 entropy
+	err := state.SeedRandao(spec, eth1BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := SetRegistry(spec, state, count, validator)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	if err := state.SetGenesisValidatorsRoot(vals.HashTreeRoot(tree.GetHashFn())); err != nil {
+		return nil, err
+	}
+	if st, ok := state.(common.SyncCommitteeBeaconState); ok {
+		// Like get_next_sync_committee in initialize_beacon_state_from_eth1: the committee of the epoch after genesis.
+		indices, err := computeSyncCommitteeIndices(spec, fork, state, common.GENESIS_EPOCH+1, active)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute sync committee indices: %v", err)
+		}
+		// Note: A duplicate committee is assigned for the current and next committee at genesis
+		syncCommittee, err := genesisSyncCommittee(indices, func(i uint64) common.BLSPubkey {
+			v, _ := validator(i)
+			return v.Pubkey
+		})
+		if err != nil {
+			return nil, err
+		}
+		syncCommitteeView, err := syncCommittee.View(spec)
+		if err != nil {
+			return nil, err
+		}
+		if err := st.SetCurrentSyncCommittee(syncCommitteeView); err != nil {
+			return nil, err
+		}
+		if err := st.SetNextSyncCommittee(syncCommitteeView); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
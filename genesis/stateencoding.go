@@ -0,0 +1,863 @@
+package genesis
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/holiman/uint256"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/view"
+)
+
+// numberListType is a list of uint8 values that are numbers, not bytes, like the participation flags of validators.
+// The SSZ types do not tell them apart from byte lists, so StateType marks them with this type.
+type numberListType struct {
+	*view.BasicListTypeDef
+}
+
+// StateType returns the SSZ type of the beacon state of the fork, with the participation lists marked as lists of numbers.
+func (f *Fork) StateType(spec *common.Spec) *view.ContainerTypeDef {
+	typ := f.NewState(spec).(interface{ Type() view.TypeDef }).Type().(*view.ContainerTypeDef)
+	participation := altair.ParticipationRegistryType(spec)
+	fields := make([]view.FieldDef, len(typ.Fields))
+	for i, field := range typ.Fields {
+		if t, ok := field.Type.(*view.BasicListTypeDef); ok && *t == *participation {
+			field.Type = &numberListType{t}
+		}
+		fields[i] = field
+	}
+	return view.ContainerType(typ.ContainerName, fields)
+}
+
+// WriteJSON writes SSZ data of the type as indented JSON, in the Beacon API encoding:
+// uints as quoted decimals, bytes and bitfields as 0x-prefixed hex, containers as objects, and lists and vectors as arrays.
+// The SSZ data is transcoded directly, without decoding it into a tree first, so large states are fast to write.
+func WriteJSON(w io.Writer, typ view.TypeDef, data []byte) error {
+	bw := bufio.NewWriter(w)
+	if err := encodeSSZ(&jsonEncoder{w: bw}, typ, data, ""); err != nil {
+		return err
+	}
+	if err := bw.WriteByte('\n'); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteYAML writes SSZ data of the type as YAML, with the same values as WriteJSON.
+func WriteYAML(w io.Writer, typ view.TypeDef, data []byte) error {
+	e := &yamlEncoder{}
+	if err := encodeSSZ(e, typ, data, ""); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(e.root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// valueEncoder writes the values of SSZ data, in the order of the data.
+type valueEncoder interface {
+	str(s string) error
+	boolean(v bool) error
+	beginObject() error
+	key(name string) error
+	beginArray() error
+	// end ends the innermost object or array.
+	end() error
+}
+
+func encodeSSZ(e valueEncoder, typ view.TypeDef, data []byte, name string) error {
+	if typ.IsFixedByteLength() && uint64(len(data)) != typ.TypeByteLength() {
+		return fmt.Errorf("%s: expected %d bytes, got %d", name, typ.TypeByteLength(), len(data))
+	}
+	switch t := typ.(type) {
+	case view.UintMeta:
+		if t == view.Uint256Type {
+			var be [32]byte
+			for i := range be {
+				be[i] = data[31-i]
+			}
+			return e.str(new(uint256.Int).SetBytes(be[:]).Dec())
+		}
+		var v uint64
+		switch t {
+		case view.Uint8Type:
+			v = uint64(data[0])
+		case view.Uint16Type:
+			v = uint64(binary.LittleEndian.Uint16(data))
+		case view.Uint32Type:
+			v = uint64(binary.LittleEndian.Uint32(data))
+		default:
+			v = binary.LittleEndian.Uint64(data)
+		}
+		return e.str(strconv.FormatUint(v, 10))
+	case view.BoolMeta:
+		if data[0] > 1 {
+			return fmt.Errorf("%s: invalid bool %d", name, data[0])
+		}
+		return e.boolean(data[0] == 1)
+	case view.RootMeta, view.SmallByteVecMeta, *view.BitVectorTypeDef, *view.BitListTypeDef:
+		return e.str(hexString(data))
+	case *view.BasicVectorTypeDef:
+		if t.ElemType == view.Uint8Type {
+			return e.str(hexString(data))
+		}
+		return encodeSSZElements(e, t.ElemType, data, t.VectorLength, t.VectorLength, name)
+	case *view.BasicListTypeDef:
+		if t.ElemType == view.Uint8Type {
+			if uint64(len(data)) > t.ListLimit {
+				return fmt.Errorf("%s: %d bytes, limit is %d", name, len(data), t.ListLimit)
+			}
+			return e.str(hexString(data))
+		}
+		return encodeSSZElements(e, t.ElemType, data, 0, t.ListLimit, name)
+	case *numberListType:
+		return encodeSSZElements(e, t.ElemType, data, 0, t.ListLimit, name)
+	case *view.ComplexVectorTypeDef:
+		return encodeSSZElements(e, t.ElemType, data, t.VectorLength, t.VectorLength, name)
+	case *view.ComplexListTypeDef:
+		return encodeSSZElements(e, t.ElemType, data, 0, t.ListLimit, name)
+	case *view.ContainerTypeDef:
+		fields, err := sszFields(t, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := e.beginObject(); err != nil {
+			return err
+		}
+		for i, f := range t.Fields {
+			if err := e.key(f.Name); err != nil {
+				return err
+			}
+			if err := encodeSSZ(e, f.Type, fields[i], f.Name); err != nil {
+				return err
+			}
+		}
+		return e.end()
+	default:
+		return fmt.Errorf("%s: unsupported type %s", name, typ)
+	}
+}
+
+// encodeSSZElements writes the elements of a list or vector, of which there must be at least minCount, and at most maxCount.
+func encodeSSZElements(e valueEncoder, elemType view.TypeDef, data []byte, minCount uint64, maxCount uint64, name string) error {
+	elems, err := sszElements(elemType, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if n := uint64(len(elems)); n < minCount || n > maxCount {
+		return fmt.Errorf("%s: %d elements, expected %d to %d", name, n, minCount, maxCount)
+	}
+	if err := e.beginArray(); err != nil {
+		return err
+	}
+	for _, elem := range elems {
+		if err := encodeSSZ(e, elemType, elem, name); err != nil {
+			return err
+		}
+	}
+	return e.end()
+}
+
+func hexString(data []byte) string {
+	return "0x" + hex.EncodeToString(data)
+}
+
+// jsonEncoder streams indented JSON.
+type jsonEncoder struct {
+	w *bufio.Writer
+	// open are the closing brackets of the open objects and arrays, innermost last.
+	open []byte
+	// empty is true if the innermost object or array has no values yet.
+	empty bool
+	// afterKey is true if the next value follows a key on the same line.
+	afterKey bool
+	buf      []byte
+}
+
+// next writes the separator and indentation before a value or key.
+func (e *jsonEncoder) next() {
+	if e.afterKey {
+		e.afterKey = false
+		return
+	}
+	if len(e.open) == 0 {
+		return
+	}
+	if !e.empty {
+		e.w.WriteByte(',')
+	}
+	e.empty = false
+	e.newline(len(e.open))
+}
+
+func (e *jsonEncoder) newline(depth int) {
+	e.w.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.w.WriteString("  ")
+	}
+}
+
+func (e *jsonEncoder) str(s string) error {
+	e.next()
+	e.buf = strconv.AppendQuote(e.buf[:0], s)
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+func (e *jsonEncoder) boolean(v bool) error {
+	e.next()
+	_, err := e.w.WriteString(strconv.FormatBool(v))
+	return err
+}
+
+func (e *jsonEncoder) beginObject() error {
+	return e.begin('{', '}')
+}
+
+func (e *jsonEncoder) beginArray() error {
+	return e.begin('[', ']')
+}
+
+func (e *jsonEncoder) begin(opening byte, closing byte) error {
+	e.next()
+	e.open = append(e.open, closing)
+	e.empty = true
+	return e.w.WriteByte(opening)
+}
+
+func (e *jsonEncoder) key(name string) error {
+	if err := e.str(name); err != nil {
+		return err
+	}
+	e.afterKey = true
+	_, err := e.w.WriteString(": ")
+	return err
+}
+
+func (e *jsonEncoder) end() error {
+	closing := e.open[len(e.open)-1]
+	e.open = e.open[:len(e.open)-1]
+	if !e.empty {
+		e.newline(len(e.open))
+	}
+	// The outer object or array has at least this value.
+	e.empty = false
+	return e.w.WriteByte(closing)
+}
+
+// yamlEncoder builds a YAML document.
+type yamlEncoder struct {
+	root *yaml.Node
+	// open are the open mappings and sequences, innermost last.
+	open []*yaml.Node
+}
+
+func (e *yamlEncoder) add(n *yaml.Node) {
+	if len(e.open) == 0 {
+		e.root = n
+		return
+	}
+	parent := e.open[len(e.open)-1]
+	parent.Content = append(parent.Content, n)
+}
+
+func (e *yamlEncoder) str(s string) error {
+	// Quoted like in JSON, so numbers and hex read back as strings everywhere.
+	e.add(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s, Style: yaml.DoubleQuotedStyle})
+	return nil
+}
+
+func (e *yamlEncoder) boolean(v bool) error {
+	e.add(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)})
+	return nil
+}
+
+func (e *yamlEncoder) beginObject() error {
+	return e.begin(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+}
+
+func (e *yamlEncoder) beginArray() error {
+	return e.begin(&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"})
+}
+
+func (e *yamlEncoder) begin(n *yaml.Node) error {
+	e.add(n)
+	e.open = append(e.open, n)
+	return nil
+}
+
+func (e *yamlEncoder) key(name string) error {
+	e.add(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name})
+	return nil
+}
+
+func (e *yamlEncoder) end() error {
+	e.open = e.open[:len(e.open)-1]
+	return nil
+}
+
+// sszFields splits the SSZ data of a container into the data of its fields.
+func sszFields(typ *view.ContainerTypeDef, data []byte) ([][]byte, error) {
+	fields := make([][]byte, len(typ.Fields))
+	// The fixed-size fields, and the offsets of the variable-size fields, in the fixed-size part.
+	var offsets []uint64
+	var variable []int
+	pos := uint64(0)
+	for i, f := range typ.Fields {
+		size := uint64(4)
+		if f.Type.IsFixedByteLength() {
+			size = f.Type.TypeByteLength()
+		}
+		if pos+size > uint64(len(data)) {
+			return nil, fmt.Errorf("container is too short: %d bytes", len(data))
+		}
+		if f.Type.IsFixedByteLength() {
+			fields[i] = data[pos : pos+size]
+		} else {
+			offsets = append(offsets, uint64(binary.LittleEndian.Uint32(data[pos:])))
+			variable = append(variable, i)
+		}
+		pos += size
+	}
+	if len(offsets) == 0 {
+		if pos != uint64(len(data)) {
+			return nil, fmt.Errorf("container has %d bytes, expected %d", len(data), pos)
+		}
+		return fields, nil
+	}
+	if offsets[0] != pos {
+		return nil, fmt.Errorf("container has first offset %d, expected %d", offsets[0], pos)
+	}
+	for j, i := range variable {
+		start, end := offsets[j], uint64(len(data))
+		if j+1 < len(offsets) {
+			end = offsets[j+1]
+		}
+		if start > end || end > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid %s offsets", typ.Fields[i].Name)
+		}
+		fields[i] = data[start:end]
+	}
+	return fields, nil
+}
+
+// sszElements splits the SSZ data of a list or vector into the data of its elements.
+func sszElements(elemType view.TypeDef, data []byte) ([][]byte, error) {
+	if elemType.IsFixedByteLength() {
+		size := elemType.TypeByteLength()
+		if uint64(len(data))%size != 0 {
+			return nil, fmt.Errorf("%d bytes is not a multiple of the element size %d", len(data), size)
+		}
+		elems := make([][]byte, uint64(len(data))/size)
+		for i := range elems {
+			elems[i] = data[uint64(i)*size : uint64(i+1)*size]
+		}
+		return elems, nil
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("invalid first offset")
+	}
+	first := uint64(binary.LittleEndian.Uint32(data))
+	if first%4 != 0 || first == 0 || first > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid first offset %d", first)
+	}
+	elems := make([][]byte, first/4)
+	for i := range elems {
+		start := uint64(binary.LittleEndian.Uint32(data[i*4:]))
+		end := uint64(len(data))
+		if i+1 < len(elems) {
+			end = uint64(binary.LittleEndian.Uint32(data[(i+1)*4:]))
+		}
+		if start > end || end > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid offset of element %d", i)
+		}
+		elems[i] = data[start:end]
+	}
+	return elems, nil
+}
+
+// ReadSSZ reads JSON or YAML of the type, as written by WriteJSON or WriteYAML, and returns the SSZ data of it.
+// Numbers may be quoted or not, all fields of containers are required.
+// JSON is decoded as a stream, so it scales to large states. YAML is parsed as a whole first.
+func ReadSSZ(r io.Reader, typ view.TypeDef) ([]byte, error) {
+	d, err := newTextDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(typ, d, "")
+}
+
+// ReadState reads a beacon state as JSON or YAML, and returns the SSZ data of it.
+// The fork is detected from fork.current_version if forkName is empty, like DetectFork.
+// The fork has to be known before the state is decoded, so the input is then read twice:
+// r is read into memory first, unless it is an io.ReadSeeker.
+func ReadState(spec *common.Spec, r io.Reader, forkName string) ([]byte, *Fork, error) {
+	var fork *Fork
+	var err error
+	if forkName != "" {
+		fork, err = ForkByName(forkName)
+	} else {
+		fork, r, err = detectTextFork(spec, r)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ReadSSZ(r, fork.StateType(spec))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s state: %w", fork.Name, err)
+	}
+	return data, fork, nil
+}
+
+// detectTextFork detects the fork of a JSON or YAML state, and returns a reader of the state from the start.
+func detectTextFork(spec *common.Spec, r io.Reader) (*Fork, io.Reader, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read state: %w", err)
+		}
+		rs = bytes.NewReader(data)
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := newTextDecoder(rs)
+	if err != nil {
+		return nil, nil, err
+	}
+	version, err := findFieldValue(d, "fork", "current_version")
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := decodeHexValue(version, d, "current_version", 4, 4)
+	if err != nil {
+		return nil, nil, err
+	}
+	fork, err := forkByVersion(spec, common.Version(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	return fork, rs, nil
+}
+
+// findFieldValue reads up to the value of the field at the path of nested objects, and returns it if it is not an object or array.
+func findFieldValue(d textDecoder, path ...string) (textToken, error) {
+	tok, err := d.next()
+	if err != nil {
+		return textToken{}, fmt.Errorf("failed to parse: %w", err)
+	}
+	for i, name := range path {
+		if tok.kind != objectToken {
+			return textToken{}, fmt.Errorf("%s (%s): expected an object", strings.Join(path[:i], "."), d.pos())
+		}
+		for {
+			key, err := d.next()
+			if err != nil {
+				return textToken{}, fmt.Errorf("failed to parse: %w", err)
+			}
+			if key.kind == endToken {
+				return textToken{}, fmt.Errorf("state has no %s", strings.Join(path[:i+1], "."))
+			}
+			if tok, err = d.next(); err != nil {
+				return textToken{}, fmt.Errorf("failed to parse: %w", err)
+			}
+			if key.value == name {
+				break
+			}
+			if err := skipValue(d, tok); err != nil {
+				return textToken{}, err
+			}
+		}
+	}
+	return tok, nil
+}
+
+// skipValue reads the rest of the value that starts with the token.
+func skipValue(d textDecoder, tok textToken) error {
+	for depth := 0; ; {
+		switch tok.kind {
+		case objectToken, arrayToken:
+			depth++
+		case endToken:
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if tok, err = d.next(); err != nil {
+			return fmt.Errorf("failed to parse: %w", err)
+		}
+	}
+}
+
+type textTokenKind int
+
+const (
+	scalarToken textTokenKind = iota
+	objectToken
+	arrayToken
+	// endToken ends the innermost object or array.
+	endToken
+)
+
+// textToken is a scalar, or the start or end of an object or array. The keys of objects are scalars.
+type textToken struct {
+	kind  textTokenKind
+	value string
+}
+
+// textDecoder reads the tokens of JSON or YAML, in the order of the document.
+type textDecoder interface {
+	next() (textToken, error)
+	// pos describes the position of the last token, for errors.
+	pos() string
+}
+
+// newTextDecoder returns a streaming decoder if the input is JSON, and a decoder of the parsed document otherwise.
+func newTextDecoder(r io.Reader) (textDecoder, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse: %w", err)
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		if err := br.UnreadByte(); err != nil {
+			return nil, err
+		}
+		if b == '{' || b == '[' {
+			dec := json.NewDecoder(br)
+			dec.UseNumber()
+			return &jsonDecoder{dec: dec}, nil
+		}
+		break
+	}
+	var doc yaml.Node
+	if err := yaml.NewDecoder(br).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	root := &doc
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		root = doc.Content[0]
+	}
+	return &yamlDecoder{root: root}, nil
+}
+
+// jsonDecoder reads the tokens of JSON as a stream.
+type jsonDecoder struct {
+	dec *json.Decoder
+	// offset is the input offset of the last token.
+	offset int64
+}
+
+func (d *jsonDecoder) next() (textToken, error) {
+	d.offset = d.dec.InputOffset()
+	tok, err := d.dec.Token()
+	if err != nil {
+		return textToken{}, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			return textToken{kind: objectToken}, nil
+		case '[':
+			return textToken{kind: arrayToken}, nil
+		default:
+			return textToken{kind: endToken}, nil
+		}
+	case string:
+		return textToken{value: v}, nil
+	case json.Number:
+		return textToken{value: v.String()}, nil
+	case bool:
+		return textToken{value: strconv.FormatBool(v)}, nil
+	default:
+		return textToken{value: "null"}, nil
+	}
+}
+
+func (d *jsonDecoder) pos() string {
+	return fmt.Sprintf("offset %d", d.offset)
+}
+
+// yamlDecoder reads the tokens of a parsed YAML document.
+type yamlDecoder struct {
+	// root is the top-level value, until it is read.
+	root *yaml.Node
+	// open are the mappings and sequences that are read, innermost last.
+	open []yamlOpenNode
+	line int
+}
+
+type yamlOpenNode struct {
+	node *yaml.Node
+	// read is the number of values that are read.
+	read int
+}
+
+func (d *yamlDecoder) next() (textToken, error) {
+	n := d.root
+	d.root = nil
+	if n == nil {
+		if len(d.open) == 0 {
+			return textToken{}, io.EOF
+		}
+		top := &d.open[len(d.open)-1]
+		if top.read == len(top.node.Content) {
+			d.open = d.open[:len(d.open)-1]
+			return textToken{kind: endToken}, nil
+		}
+		n = top.node.Content[top.read]
+		top.read++
+	}
+	d.line = n.Line
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return textToken{value: n.Value}, nil
+	case yaml.MappingNode:
+		d.open = append(d.open, yamlOpenNode{node: n})
+		return textToken{kind: objectToken}, nil
+	case yaml.SequenceNode:
+		d.open = append(d.open, yamlOpenNode{node: n})
+		return textToken{kind: arrayToken}, nil
+	default:
+		return textToken{}, fmt.Errorf("line %d: unsupported YAML node", n.Line)
+	}
+}
+
+func (d *yamlDecoder) pos() string {
+	return fmt.Sprintf("line %d", d.line)
+}
+
+// decodeValue decodes the next value of the decoder.
+func decodeValue(typ view.TypeDef, d textDecoder, name string) ([]byte, error) {
+	tok, err := d.next()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to parse: %w", name, err)
+	}
+	return decodeTokenValue(typ, tok, d, name)
+}
+
+// decodeTokenValue decodes the value that starts with the token.
+func decodeTokenValue(typ view.TypeDef, tok textToken, d textDecoder, name string) ([]byte, error) {
+	switch t := typ.(type) {
+	case view.UintMeta:
+		if tok.kind != scalarToken {
+			return nil, fmt.Errorf("%s (%s): expected a number", name, d.pos())
+		}
+		if t == view.Uint256Type {
+			v, err := uint256.FromDecimal(tok.value)
+			if err != nil {
+				return nil, fmt.Errorf("%s (%s): invalid uint256 %q: %w", name, d.pos(), tok.value, err)
+			}
+			be := v.Bytes32()
+			out := make([]byte, 32)
+			for i := range out {
+				out[i] = be[31-i]
+			}
+			return out, nil
+		}
+		v, err := strconv.ParseUint(tok.value, 10, int(t)*8)
+		if err != nil {
+			return nil, fmt.Errorf("%s (%s): invalid uint%d %q", name, d.pos(), int(t)*8, tok.value)
+		}
+		out := binary.LittleEndian.AppendUint64(nil, v)
+		return out[:t], nil
+	case view.BoolMeta:
+		switch {
+		case tok.kind == scalarToken && tok.value == "true":
+			return []byte{1}, nil
+		case tok.kind == scalarToken && tok.value == "false":
+			return []byte{0}, nil
+		default:
+			return nil, fmt.Errorf("%s (%s): expected true or false", name, d.pos())
+		}
+	case view.RootMeta, view.SmallByteVecMeta:
+		return decodeHexValue(tok, d, name, typ.TypeByteLength(), typ.TypeByteLength())
+	case *view.BitVectorTypeDef:
+		out, err := decodeHexValue(tok, d, name, (t.BitLength+7)/8, (t.BitLength+7)/8)
+		if err != nil {
+			return nil, err
+		}
+		if t.BitLength%8 != 0 && out[len(out)-1]>>(t.BitLength%8) != 0 {
+			return nil, fmt.Errorf("%s (%s): bits set after the %d bits of the bitvector", name, d.pos(), t.BitLength)
+		}
+		return out, nil
+	case *view.BitListTypeDef:
+		out, err := decodeHexValue(tok, d, name, 1, (t.BitLimit>>3)+1)
+		if err != nil {
+			return nil, err
+		}
+		last := out[len(out)-1]
+		if last == 0 {
+			return nil, fmt.Errorf("%s (%s): bitlist has no delimiter bit", name, d.pos())
+		}
+		bitLength := uint64(len(out)-1)*8 + uint64(bits.Len8(last)) - 1
+		if bitLength > t.BitLimit {
+			return nil, fmt.Errorf("%s (%s): %d bits, limit is %d", name, d.pos(), bitLength, t.BitLimit)
+		}
+		return out, nil
+	case *view.BasicVectorTypeDef:
+		if t.ElemType == view.Uint8Type {
+			return decodeHexValue(tok, d, name, t.VectorLength, t.VectorLength)
+		}
+		return decodeElements(t.ElemType, tok, d, t.VectorLength, t.VectorLength, name)
+	case *view.BasicListTypeDef:
+		if t.ElemType == view.Uint8Type {
+			return decodeHexValue(tok, d, name, 0, t.ListLimit)
+		}
+		return decodeElements(t.ElemType, tok, d, 0, t.ListLimit, name)
+	case *numberListType:
+		return decodeElements(t.ElemType, tok, d, 0, t.ListLimit, name)
+	case *view.ComplexVectorTypeDef:
+		return decodeElements(t.ElemType, tok, d, t.VectorLength, t.VectorLength, name)
+	case *view.ComplexListTypeDef:
+		return decodeElements(t.ElemType, tok, d, 0, t.ListLimit, name)
+	case *view.ContainerTypeDef:
+		if tok.kind != objectToken {
+			return nil, fmt.Errorf("%s (%s): expected an object", name, d.pos())
+		}
+		pos := d.pos()
+		fieldIndex := make(map[string]int, len(t.Fields))
+		for i, f := range t.Fields {
+			fieldIndex[f.Name] = i
+		}
+		fields := make([][]byte, len(t.Fields))
+		for {
+			key, err := d.next()
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to parse: %w", name, err)
+			}
+			if key.kind == endToken {
+				break
+			}
+			i, ok := fieldIndex[key.value]
+			if key.kind != scalarToken || !ok {
+				return nil, fmt.Errorf("%s (%s): unknown field %s", name, d.pos(), key.value)
+			}
+			if fields[i] != nil {
+				return nil, fmt.Errorf("%s (%s): duplicate field %s", name, d.pos(), key.value)
+			}
+			data, err := decodeValue(t.Fields[i].Type, d, key.value)
+			if err != nil {
+				return nil, err
+			}
+			// Non-nil, to tell decoded empty fields from missing fields.
+			if data == nil {
+				data = []byte{}
+			}
+			fields[i] = data
+		}
+		for i, f := range t.Fields {
+			if fields[i] == nil {
+				return nil, fmt.Errorf("%s (%s): missing field %s", name, pos, f.Name)
+			}
+		}
+		return sszConcat(fields, func(i int) bool { return t.Fields[i].Type.IsFixedByteLength() }), nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported type %s", name, typ)
+	}
+}
+
+// decodeElements decodes the elements of a list or vector that starts with the token,
+// of which there must be at least minCount, and at most maxCount.
+func decodeElements(elemType view.TypeDef, tok textToken, d textDecoder, minCount uint64, maxCount uint64, name string) ([]byte, error) {
+	if tok.kind != arrayToken {
+		return nil, fmt.Errorf("%s (%s): expected a list", name, d.pos())
+	}
+	pos := d.pos()
+	var elems [][]byte
+	for {
+		tok, err := d.next()
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to parse: %w", name, err)
+		}
+		if tok.kind == endToken {
+			break
+		}
+		if uint64(len(elems)) == maxCount {
+			return nil, fmt.Errorf("%s (%s): more than %d elements", name, pos, maxCount)
+		}
+		elem, err := decodeTokenValue(elemType, tok, d, name)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	if count := uint64(len(elems)); count < minCount {
+		return nil, fmt.Errorf("%s (%s): %d elements, expected %d to %d", name, pos, count, minCount, maxCount)
+	}
+	return sszConcat(elems, func(int) bool { return elemType.IsFixedByteLength() }), nil
+}
+
+// sszConcat joins the SSZ data of the fields or elements, with offsets for the variable-size ones.
+func sszConcat(parts [][]byte, fixed func(i int) bool) []byte {
+	fixedLength := 0
+	for i, p := range parts {
+		if fixed(i) {
+			fixedLength += len(p)
+		} else {
+			fixedLength += 4
+		}
+	}
+	out := make([]byte, 0, fixedLength)
+	offset := fixedLength
+	for i, p := range parts {
+		if fixed(i) {
+			out = append(out, p...)
+		} else {
+			out = binary.LittleEndian.AppendUint32(out, uint32(offset))
+			offset += len(p)
+		}
+	}
+	for i, p := range parts {
+		if !fixed(i) {
+			out = append(out, p...)
+		}
+	}
+	return out
+}
+
+// decodeHexValue decodes the token as 0x-prefixed hex of minLength to maxLength bytes.
+func decodeHexValue(tok textToken, d textDecoder, name string, minLength uint64, maxLength uint64) ([]byte, error) {
+	if tok.kind != scalarToken || !strings.HasPrefix(tok.value, "0x") {
+		return nil, fmt.Errorf("%s (%s): expected 0x-prefixed hex", name, d.pos())
+	}
+	out, err := hex.DecodeString(tok.value[2:])
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): invalid hex: %w", name, d.pos(), err)
+	}
+	if l := uint64(len(out)); l < minLength || l > maxLength {
+		if minLength == maxLength {
+			return nil, fmt.Errorf("%s (%s): expected %d bytes, got %d", name, d.pos(), minLength, l)
+		}
+		return nil, fmt.Errorf("%s (%s): %d bytes, expected %d to %d", name, d.pos(), l, minLength, maxLength)
+	}
+	return out, nil
+}
@@ -0,0 +1,180 @@
+package genesis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/view"
+)
+
+func TestWriteJSON(t *testing.T) {
+	spec := configs.Minimal
+	res, err := Build(context.Background(), &Options{
+		Spec:          spec,
+		Fork:          "deneb",
+		Eth1BlockHash: common.Root{1},
+		Validators:    testValidators(t, spec, 64),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stateData bytes.Buffer
+	if err := res.State.Serialize(codec.NewEncodingWriter(&stateData)); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := WriteJSON(&out, res.Fork.StateType(spec), stateData.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	var got any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	// Same as the JSON of the zrnt state type
+	var state deneb.BeaconState
+	if err := state.Deserialize(spec, codec.NewDecodingReader(bytes.NewReader(stateData.Bytes()), uint64(stateData.Len()))); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected any
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatal(err)
+	}
+	// zrnt encodes empty lists as null
+	for _, field := range []string{"eth1_data_votes", "historical_roots", "historical_summaries"} {
+		expected.(map[string]any)[field] = []any{}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected JSON:\n%s", out.String())
+	}
+
+	if err := WriteJSON(&out, res.Fork.StateType(spec), stateData.Bytes()[:stateData.Len()-1]); err == nil {
+		t.Fatal("expected error for truncated state")
+	}
+}
+
+func TestReadState(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ELECTRA_FORK_EPOCH = 0
+	spec.FULU_FORK_EPOCH = 0
+	res, err := Build(context.Background(), &Options{
+		Spec:          &spec,
+		Fork:          "fulu",
+		Eth1BlockHash: common.Root{1},
+		Validators:    testValidators(t, &spec, 64),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stateData bytes.Buffer
+	if err := res.State.Serialize(codec.NewEncodingWriter(&stateData)); err != nil {
+		t.Fatal(err)
+	}
+	typ := res.Fork.StateType(&spec)
+	for _, write := range []func(io.Writer, view.TypeDef, []byte) error{WriteJSON, WriteYAML} {
+		var text bytes.Buffer
+		if err := write(&text, typ, stateData.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		data, fork, err := ReadState(&spec, &text, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fork.Name != "fulu" || !bytes.Equal(data, stateData.Bytes()) {
+			t.Fatalf("%s state does not match", fork.Name)
+		}
+	}
+
+	// Hand-edited states may have unquoted numbers.
+	var text bytes.Buffer
+	if err := WriteJSON(&text, typ, stateData.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(text.String(), `"slot": "0"`, `"slot": 8`, 1)
+	data, _, err := ReadState(&spec, strings.NewReader(edited), "fulu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, err := DecodeState(&spec, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slot, err := state.Slot(); err != nil || slot != 8 {
+		t.Fatalf("unexpected slot %d", slot)
+	}
+
+	for name, invalid := range map[string]string{
+		"unknown field":     strings.Replace(text.String(), `"slot":`, `"slott":`, 1),
+		"missing field":     strings.Replace(text.String(), `"slot": "0",`, ``, 1),
+		"invalid number":    strings.Replace(text.String(), `"slot": "0"`, `"slot": "-1"`, 1),
+		"bitvector length":  strings.Replace(text.String(), `"justification_bits": "0x00"`, `"justification_bits": "0x0000"`, 1),
+		"bitvector padding": strings.Replace(text.String(), `"justification_bits": "0x00"`, `"justification_bits": "0x10"`, 1),
+		"vector length":     strings.Replace(text.String(), `"randao_mixes": [`, `"randao_mixes": ["0x00",`, 1),
+	} {
+		if _, _, err := ReadState(&spec, strings.NewReader(invalid), ""); err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
+
+func TestReadSSZ(t *testing.T) {
+	// Byte lists are hex by their type, also with the name of a participation list.
+	typ := view.ContainerType("Test", []view.FieldDef{
+		{Name: "previous_epoch_participation", Type: view.BasicListType(view.Uint8Type, 4)},
+		{Name: "participation", Type: &numberListType{view.BasicListType(view.Uint8Type, 4)}},
+		{Name: "values", Type: view.BasicListType(view.Uint64Type, 2)},
+	})
+	data := []byte{12, 0, 0, 0, 14, 0, 0, 0, 16, 0, 0, 0, 0xab, 0xcd, 3, 7, 5, 0, 0, 0, 0, 0, 0, 0}
+	var out bytes.Buffer
+	if err := WriteJSON(&out, typ, data); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"previous_epoch_participation":"0xabcd","participation":["3","7"],"values":["5"]}`
+	if got := strings.Join(strings.Fields(out.String()), ""); got != expected {
+		t.Fatalf("unexpected JSON %s", got)
+	}
+
+	// JSON is read as a stream, from any reader, with fields in any order.
+	for _, text := range []string{
+		out.String(),
+		`{"values": [5], "participation": [3, 7], "previous_epoch_participation": "0xabcd"}`,
+		"previous_epoch_participation: '0xabcd'\nparticipation: [3, 7]\nvalues:\n  - 5\n",
+	} {
+		got, err := ReadSSZ(io.MultiReader(strings.NewReader(text)), typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("unexpected SSZ %x", got)
+		}
+	}
+
+	for name, invalid := range map[string]string{
+		"list limit":      `{"previous_epoch_participation": "0x", "participation": [], "values": [1, 2, 3]}`,
+		"duplicate field": `{"previous_epoch_participation": "0x", "participation": [], "participation": [], "values": []}`,
+		"missing field":   `{"previous_epoch_participation": "0x", "values": []}`,
+		"number list":     `{"previous_epoch_participation": "0x", "participation": "0x0307", "values": []}`,
+		"truncated":       `{"previous_epoch_participation": "0x", "participation": [3, 7`,
+	} {
+		if _, err := ReadSSZ(strings.NewReader(invalid), typ); err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package genesis
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// COMPOUNDING_WITHDRAWAL_PREFIX is the withdrawal credentials prefix of compounding validators, introduced in Electra.
+const COMPOUNDING_WITHDRAWAL_PREFIX = 0x02
+
+// GenerateValidatorKeysByMnemonic derives the validators of each mnemonic, and writes their pubkeys to a file per tranche.
+// If keystoresExport is not nil, the EIP-2335 keystores of the validators are also written per tranche.
+// If pubkeyCacheDir is not empty, derived pubkeys are cached there, and only uncached pubkeys are derived.
+// The progress is written to log, if not nil.
+func GenerateValidatorKeysByMnemonic(spec *common.Spec, mnemonicsConfigPath string, tranchesDir string, ethWithdrawalAddress common.Eth1Address, keystoresExport *KeystoresExport, pubkeyCacheDir string, log io.Writer) ([]phase0.KickstartValidatorData, error) {
+	validators, _, err := generateValidatorKeysByMnemonic(spec, mnemonicsConfigPath, tranchesDir, ethWithdrawalAddress, keystoresExport, pubkeyCacheDir, logWriter(log))
+	return validators, err
+}
+
+func generateValidatorKeysByMnemonic(spec *common.Spec, mnemonicsConfigPath string, tranchesDir string, ethWithdrawalAddress common.Eth1Address, keystoresExport *KeystoresExport, pubkeyCacheDir string, log io.Writer) ([]phase0.KickstartValidatorData, []MnemonicSrc, error) {
+	mnemonics, err := LoadMnemonics(mnemonicsConfigPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", mnemonicsConfigPath, err)
+	}
+	if keystoresExport != nil {
+		if err := keystoresExport.Check(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The validators are derived concurrently, into a slice of the final size: the keys are derived once,
+	// and SetRegistry reads the validators more than once, out of order, so they are not streamed.
+	var valCount uint64 = 0
+	for _, mnemonicSrc := range mnemonics {
+		valCount += mnemonicSrc.Count
+	}
+	validators := make([]phase0.KickstartValidatorData, valCount)
+
+	// Check all mnemonics before deriving any keys, and report all problems at once
+	var problems []error
+	names := make(map[string]int, len(mnemonics))
+	for m := range mnemonics {
+		mnemonicSrc := &mnemonics[m]
+		problem := func(format string, args ...any) {
+			problems = append(problems, fmt.Errorf("%s line %d (mnemonic %d): %s", mnemonicsConfigPath, mnemonicSrc.line, m, fmt.Sprintf(format, args...)))
+		}
+		name := mnemonicSrc.TrancheName(m)
+		if name == "." || name == ".." || filepath.Base(name) != name {
+			problem("invalid name %q", name)
+		}
+		if other, ok := names[name]; ok {
+			problem("same name %q as mnemonic %d", name, other)
+		}
+		names[name] = m
+		if _, _, err := mnemonicSrc.withdrawalPrefix(ethWithdrawalAddress); err != nil {
+			problem("%v", err)
+		}
+		if _, err := seedFromMnemonic(mnemonicSrc.Mnemonic, mnemonicSrc.Passphrase); err != nil {
+			problem("%v", err)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, nil, errors.Join(problems...)
+	}
+
+	offset := uint64(0)
+	for m, mnemonicSrc := range mnemonics {
+		var g errgroup.Group
+		g.SetLimit(10_000) // when generating large states, do squeeze processing, but do not go out of memory
+
+		var prog int32
+		prefix, withdrawalAddress, _ := mnemonicSrc.withdrawalPrefix(ethWithdrawalAddress)
+		balance := mnemonicSrc.Balance
+		if balance == 0 {
+			// Max effective balance by default for activation
+			balance = spec.MAX_EFFECTIVE_BALANCE
+		}
+		trancheName := mnemonicSrc.TrancheName(m)
+		fmt.Fprintf(log, "processing mnemonic %d (%s), for %d validators, starting at index %d\n", m, trancheName, mnemonicSrc.Count, mnemonicSrc.Start)
+		seed, _ := seedFromMnemonic(mnemonicSrc.Mnemonic, mnemonicSrc.Passphrase)
+		var sks []*blsu.SecretKey
+		if keystoresExport != nil {
+			sks = make([]*blsu.SecretKey, mnemonicSrc.Count)
+		}
+		var cache *mnemonicPubkeyCache
+		var cachedCount int32
+		if pubkeyCacheDir != "" {
+			cache, err = openMnemonicPubkeyCache(pubkeyCacheDir, seed)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		for i := uint64(0); i < mnemonicSrc.Count; i++ {
+			valIndex := offset + i
+			trancheIndex := i
+			idx := mnemonicSrc.Start + i
+			g.Go(func() error {
+				// BLS signing key. Derived from the cache, unless the secret key is needed for the keystores export.
+				var data phase0.KickstartValidatorData
+				if sks != nil {
+					sk, pub, err := deriveKey(seed, validatorKeyName(idx))
+					if err != nil {
+						return err
+					}
+					sks[trancheIndex] = sk
+					data.Pubkey = pub
+				} else {
+					pub, cached, err := cache.pubkey(seed, signingKeyKind, idx)
+					if err != nil {
+						return err
+					}
+					if cached {
+						atomic.AddInt32(&cachedCount, 1)
+					}
+					data.Pubkey = pub
+				}
+
+				if prefix == common.BLS_WITHDRAWAL_PREFIX {
+					// BLS withdrawal credentials
+					withdrawalPub, _, err := cache.pubkey(seed, withdrawalKeyKind, idx)
+					if err != nil {
+						return err
+					}
+					h := sha256.New()
+					h.Write(withdrawalPub[:])
+					copy(data.WithdrawalCredentials[:], h.Sum(nil))
+					data.WithdrawalCredentials[0] = common.BLS_WITHDRAWAL_PREFIX
+				} else {
+					// spec:
+					// The withdrawal_credentials field must be such that:
+					//   withdrawal_credentials[:1] == ETH1_ADDRESS_WITHDRAWAL_PREFIX (or COMPOUNDING_WITHDRAWAL_PREFIX)
+					//   withdrawal_credentials[1:12] == b'\x00' * 11
+					//   withdrawal_credentials[12:] == eth1_withdrawal_address
+					data.WithdrawalCredentials[0] = prefix
+					copy(data.WithdrawalCredentials[12:], withdrawalAddress[:])
+				}
+
+				data.Balance = balance
+				validators[valIndex] = data
+				count := atomic.AddInt32(&prog, 1)
+				if count%100 == 0 {
+					fmt.Fprintf(log, "...validator %d/%d\n", count, mnemonicSrc.Count)
+				}
+				return nil
+			})
+
+		}
+		offset += mnemonicSrc.Count
+		err := g.Wait()
+		if cache != nil {
+			if closeErr := cache.Close(); err == nil {
+				err = closeErr
+			}
+			fmt.Fprintf(log, "%d of %d validator pubkeys were cached\n", cachedCount, mnemonicSrc.Count)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fmt.Fprintln(log, "writing pubkeys list file...")
+		if err := outputPubkeys(filepath.Join(tranchesDir, trancheName+".txt"), validators[offset-mnemonicSrc.Count:offset]); err != nil {
+			return nil, nil, err
+		}
+		if keystoresExport != nil {
+			indices := make([]uint64, mnemonicSrc.Count)
+			for i := range indices {
+				indices[i] = mnemonicSrc.Start + uint64(i)
+			}
+			if err := keystoresExport.WriteTranche(trancheName, sks, indices, log); err != nil {
+				return nil, nil, fmt.Errorf("failed to write keystores of mnemonic %d: %w", m, err)
+			}
+		}
+	}
+	return validators, mnemonics, nil
+}
+
+func validatorKeyName(i uint64) string {
+	return fmt.Sprintf("m/12381/3600/%d/0/0", i)
+}
+
+func withdrawalKeyName(i uint64) string {
+	return fmt.Sprintf("m/12381/3600/%d/0", i)
+}
+
+func seedFromMnemonic(mnemonic string, passphrase string) (seed []byte, err error) {
+	mnemonic = strings.TrimSpace(mnemonic)
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, errors.New("mnemonic is not valid")
+	}
+	return bip39.NewSeed(mnemonic, passphrase), nil
+}
+
+// outputPubkeys writes the pubkeys of the validators to a file, one 0x-prefixed hex pubkey per line.
+func outputPubkeys(outPath string, validators []phase0.KickstartValidatorData) error {
+	f, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for i := range validators {
+		if _, err := w.WriteString(validators[i].Pubkey.String() + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+type MnemonicSrc struct {
+	Mnemonic string `yaml:"mnemonic"`
+	Count    uint64 `yaml:"count"`
+	// Start is the index of the first validator key path (m/12381/3600/<index>/0/0) of the mnemonic.
+	Start uint64 `yaml:"start"`
+	// Passphrase is the optional BIP-39 passphrase of the mnemonic.
+	Passphrase string `yaml:"passphrase"`
+	// WithdrawalAddress of the validators. The global eth1 withdrawal address if zero.
+	WithdrawalAddress common.Eth1Address `yaml:"withdrawal_address"`
+	// WithdrawalType is the withdrawal credentials prefix of the validators: 0x00 (BLS), 0x01 (eth1 address)
+	// or 0x02 (compounding, electra and later). If empty: 0x01 if there is a withdrawal address, 0x00 otherwise.
+	WithdrawalType string `yaml:"withdrawal_type"`
+	// Balance of the validators. MAX_EFFECTIVE_BALANCE if zero.
+	Balance common.Gwei `yaml:"balance"`
+	// Name of the tranche, for the pubkeys file and exported keystores. tranche_<mnemonic index> if empty.
+	Name string `yaml:"name"`
+
+	// line of the entry in the mnemonics YAML, for error reports
+	line int
+}
+
+// TrancheName is the name of the tranche of the validators of the m-th mnemonic.
+func (src *MnemonicSrc) TrancheName(m int) string {
+	if src.Name != "" {
+		return src.Name
+	}
+	return fmt.Sprintf("tranche_%04d", m)
+}
+
+// withdrawalPrefix returns the withdrawal credentials prefix of the mnemonic validators, and the withdrawal address if any.
+func (src *MnemonicSrc) withdrawalPrefix(ethWithdrawalAddress common.Eth1Address) (byte, common.Eth1Address, error) {
+	addr := src.WithdrawalAddress
+	if addr == (common.Eth1Address{}) {
+		addr = ethWithdrawalAddress
+	}
+	var prefix byte
+	switch strings.ToLower(src.WithdrawalType) {
+	case "":
+		if addr != (common.Eth1Address{}) {
+			prefix = common.ETH1_ADDRESS_WITHDRAWAL_PREFIX
+		} else {
+			prefix = common.BLS_WITHDRAWAL_PREFIX
+		}
+	case "0x00", "0", "bls":
+		prefix = common.BLS_WITHDRAWAL_PREFIX
+	case "0x01", "1", "eth1":
+		prefix = common.ETH1_ADDRESS_WITHDRAWAL_PREFIX
+	case "0x02", "2", "compounding":
+		prefix = COMPOUNDING_WITHDRAWAL_PREFIX
+	default:
+		return 0, addr, fmt.Errorf("unknown withdrawal type %q, expected 0x00, 0x01 or 0x02", src.WithdrawalType)
+	}
+	switch prefix {
+	case common.BLS_WITHDRAWAL_PREFIX:
+		if src.WithdrawalAddress != (common.Eth1Address{}) {
+			return 0, addr, fmt.Errorf("withdrawal address %s is set, but the withdrawal type is 0x00", src.WithdrawalAddress)
+		}
+	case COMPOUNDING_WITHDRAWAL_PREFIX:
+		if addr == (common.Eth1Address{}) {
+			return 0, addr, errors.New("compounding validators require an eth1 withdrawal address")
+		}
+	default:
+		if addr == (common.Eth1Address{}) {
+			return 0, addr, errors.New("0x01 validators require an eth1 withdrawal address")
+		}
+	}
+	return prefix, addr, nil
+}
+
+func LoadMnemonics(srcPath string) ([]MnemonicSrc, error) {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var doc yaml.Node
+	dec := yaml.NewDecoder(f)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("expected a list of mnemonics")
+	}
+	items := doc.Content[0].Content
+	data := make([]MnemonicSrc, len(items))
+	for i, item := range items {
+		if err := item.Decode(&data[i]); err != nil {
+			return nil, err
+		}
+		data[i].line = item.Line
+	}
+	return data, nil
+}
+
+// LoadValidatorsFromFile loads a list of validators, formatted as <pubkey>:<withdrawal credentials>[:<balance>] per line.
+// The problems of all lines are reported at once.
+func LoadValidatorsFromFile(spec *common.Spec, validatorsConfigPath string) ([]phase0.KickstartValidatorData, error) {
+	validators, _, err := loadValidatorsFromFile(spec, validatorsConfigPath)
+	return validators, err
+}
+
+// loadValidatorsFromFile also returns the line number of each validator.
+func loadValidatorsFromFile(spec *common.Spec, validatorsConfigPath string) ([]phase0.KickstartValidatorData, []int, error) {
+	validatorsFile, err := os.Open(validatorsConfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer validatorsFile.Close()
+
+	validators := make([]phase0.KickstartValidatorData, 0)
+	var lines []int
+	var problems []error
+	pubkeyMap := map[common.BLSPubkey]int{}
+
+	scanner := bufio.NewScanner(validatorsFile)
+	lineNum := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		validatorEntry, err := parseValidatorLine(spec, line)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s line %d: %w", validatorsConfigPath, lineNum, err))
+			continue
+		}
+		if other, ok := pubkeyMap[validatorEntry.Pubkey]; ok {
+			problems = append(problems, fmt.Errorf("%s line %d: duplicate pubkey, also on line %d", validatorsConfigPath, lineNum, other))
+			continue
+		}
+		pubkeyMap[validatorEntry.Pubkey] = lineNum
+		validators = append(validators, validatorEntry)
+		lines = append(lines, lineNum)
+	}
+	if err := scanner.Err(); err != nil {
+		problems = append(problems, fmt.Errorf("%s: %w", validatorsConfigPath, err))
+	}
+	if len(problems) > 0 {
+		return nil, nil, errors.Join(problems...)
+	}
+	return validators, lines, nil
+}
+
+func parseValidatorLine(spec *common.Spec, line string) (phase0.KickstartValidatorData, error) {
+	lineParts := strings.Split(line, ":")
+	validatorEntry := phase0.KickstartValidatorData{}
+	if len(lineParts) < 2 || len(lineParts) > 3 {
+		return validatorEntry, errors.New("expected <pubkey>:<withdrawal credentials>[:<balance>]")
+	}
+
+	// Public key
+	pubKey, err := hex.DecodeString(strings.Replace(lineParts[0], "0x", "", -1))
+	if err != nil {
+		return validatorEntry, fmt.Errorf("invalid pubkey: %w", err)
+	}
+	if len(pubKey) != 48 {
+		return validatorEntry, errors.New("invalid pubkey (invalid length)")
+	}
+	copy(validatorEntry.Pubkey[:], pubKey)
+
+	// Withdrawal credentials
+	withdrawalCred, err := hex.DecodeString(strings.Replace(lineParts[1], "0x", "", -1))
+	if err != nil {
+		return validatorEntry, fmt.Errorf("invalid withdrawal credentials: %w", err)
+	}
+	if len(withdrawalCred) != 32 {
+		return validatorEntry, errors.New("invalid withdrawal credentials (invalid length)")
+	}
+	switch withdrawalCred[0] {
+	case 0x00:
+		break
+	case 0x01, COMPOUNDING_WITHDRAWAL_PREFIX:
+		if !bytes.Equal(withdrawalCred[1:12], []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}) {
+			return validatorEntry, fmt.Errorf("invalid withdrawal credentials (invalid 0x%02x cred)", withdrawalCred[0])
+		}
+		break
+	default:
+		return validatorEntry, errors.New("invalid withdrawal credentials (invalid type)")
+	}
+	copy(validatorEntry.WithdrawalCredentials[:], withdrawalCred)
+
+	// Validator balance
+	if len(lineParts) > 2 {
+		balance, err := strconv.ParseUint(string(lineParts[2]), 10, 64)
+		if err != nil {
+			return validatorEntry, fmt.Errorf("invalid balance: %w", err)
+		}
+		validatorEntry.Balance = common.Gwei(balance)
+	} else {
+		validatorEntry.Balance = spec.MAX_EFFECTIVE_BALANCE
+	}
+	return validatorEntry, nil
+}
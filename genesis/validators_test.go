@@ -0,0 +1,128 @@
+package genesis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	blshd "github.com/protolambda/bls12-381-hd"
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestMnemonicOptions(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	mnemonicsPath := filepath.Join(dir, "mnemonics.yaml")
+	writeTestFile(t, mnemonicsPath, []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  name: operator-a
+  count: 2
+  start: 5
+  passphrase: "secret"
+  withdrawal_type: 0x01
+  withdrawal_address: "0x000000000000000000000000000000000000dEaD"
+  balance: 64000000000
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 1
+  withdrawal_type: 0x02
+  withdrawal_address: "0x000000000000000000000000000000000000bEEF"
+  balance: 2048000000000
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 1
+`))
+	validators, err := GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, dir, common.Eth1Address{}, nil, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 4 {
+		t.Fatalf("expected 4 validators, got %d", len(validators))
+	}
+
+	// the first mnemonic starts at key index 5, with the passphrase
+	seed, err := seedFromMnemonic("test test test test test test test test test test test junk", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingSK, err := blshd.SecretKeyFromHD(seed, validatorKeyName(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(signingSK); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := blsu.SkToPk(&sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validators[0].Pubkey != pub.Serialize() {
+		t.Fatalf("unexpected pubkey of first validator: %s", validators[0].Pubkey)
+	}
+
+	expectedCredentials := []common.Root{
+		{0x01, 30: 0xde, 31: 0xad},
+		{0x01, 30: 0xde, 31: 0xad},
+		{0x02, 30: 0xbe, 31: 0xef},
+	}
+	for i, exp := range expectedCredentials {
+		if validators[i].WithdrawalCredentials != exp {
+			t.Fatalf("validator %d: unexpected withdrawal credentials %s", i, validators[i].WithdrawalCredentials)
+		}
+	}
+	if validators[3].WithdrawalCredentials[0] != common.BLS_WITHDRAWAL_PREFIX {
+		t.Fatalf("validator 3: expected BLS withdrawal credentials, got %s", validators[3].WithdrawalCredentials)
+	}
+	for i, exp := range []common.Gwei{64_000_000_000, 64_000_000_000, 2048_000_000_000, spec.MAX_EFFECTIVE_BALANCE} {
+		if validators[i].Balance != exp {
+			t.Fatalf("validator %d: unexpected balance %d", i, validators[i].Balance)
+		}
+	}
+
+	for _, name := range []string{"operator-a.txt", "tranche_0001.txt", "tranche_0002.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("expected tranche file %s: %v", name, err)
+		}
+	}
+}
+
+func TestMnemonicOptionsInvalid(t *testing.T) {
+	spec := configs.Minimal
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"eth1 type without address", `withdrawal_type: 0x01`, "require an eth1 withdrawal address"},
+		{"compounding without address", `withdrawal_type: 0x02`, "require an eth1 withdrawal address"},
+		{"bls type with address", "withdrawal_type: 0x00\n  withdrawal_address: \"0x000000000000000000000000000000000000dEaD\"", "withdrawal type is 0x00"},
+		{"unknown type", `withdrawal_type: 0x03`, "unknown withdrawal type"},
+		{"path in name", `name: ../escape`, "invalid name"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			mnemonicsPath := filepath.Join(dir, "mnemonics.yaml")
+			writeTestFile(t, mnemonicsPath, []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 1
+  `+tc.config+"\n"))
+			_, err := GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, dir, common.Eth1Address{}, nil, "", nil)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	mnemonicsPath := filepath.Join(dir, "mnemonics.yaml")
+	writeTestFile(t, mnemonicsPath, []byte(`
+- {mnemonic: "test test test test test test test test test test test junk", count: 1, name: ops}
+- {mnemonic: "test test test test test test test test test test test junk", count: 1, start: 1, name: ops}
+`))
+	if _, err := GenerateValidatorKeysByMnemonic(spec, mnemonicsPath, dir, common.Eth1Address{}, nil, "", nil); err == nil || !strings.Contains(err.Error(), "same name") {
+		t.Fatalf("expected duplicate name error, got %v", err)
+	}
+}
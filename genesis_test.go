@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+// testEth1Genesis is an execution-layer genesis with all upgrades up to and including Osaka active at genesis.
+func testEth1Genesis() *core.Genesis {
+	return &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainID:                 big.NewInt(5), // DEPOSIT_CHAIN_ID of the minimal config
+			HomesteadBlock:          big.NewInt(0),
+			EIP150Block:             big.NewInt(0),
+			EIP155Block:             big.NewInt(0),
+			EIP158Block:             big.NewInt(0),
+			ByzantiumBlock:          big.NewInt(0),
+			ConstantinopleBlock:     big.NewInt(0),
+			PetersburgBlock:         big.NewInt(0),
+			IstanbulBlock:           big.NewInt(0),
+			MuirGlacierBlock:        big.NewInt(0),
+			BerlinBlock:             big.NewInt(0),
+			LondonBlock:             big.NewInt(0),
+			ArrowGlacierBlock:       big.NewInt(0),
+			GrayGlacierBlock:        big.NewInt(0),
+			MergeNetsplitBlock:      big.NewInt(0),
+			ShanghaiTime:            new(uint64),
+			CancunTime:              new(uint64),
+			PragueTime:              new(uint64),
+			OsakaTime:               new(uint64),
+			TerminalTotalDifficulty: big.NewInt(0),
+			BlobScheduleConfig: &params.BlobScheduleConfig{
+				Cancun: params.DefaultCancunBlobConfig,
+				Prague: params.DefaultPragueBlobConfig,
+			},
+		},
+		Timestamp:  1740340649,
+		GasLimit:   36_000_000,
+		Difficulty: big.NewInt(0),
+		Alloc: types.GenesisAlloc{
+			gethcommon.HexToAddress("0x0"): types.Account{
+				Balance: new(big.Int).Mul(big.NewInt(1000e9), big.NewInt(1e9)),
+				Nonce:   1,
+			},
+		},
+		BaseFee:       big.NewInt(7),
+		ExcessBlobGas: new(uint64),
+		BlobGasUsed:   new(uint64),
+	}
+}
+
+// writeTestConfig writes the minimal config, with the fork and all forks before it at the genesis epoch, and returns the path.
+func writeTestConfig(t *testing.T, dir string, forkName string) string {
+	fork, err := genesis.ForkByName(forkName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := configs.Minimal.Config
+	for _, epoch := range []struct {
+		fork  string
+		epoch *common.Epoch
+	}{
+		{"altair", &cfg.ALTAIR_FORK_EPOCH},
+		{"bellatrix", &cfg.BELLATRIX_FORK_EPOCH},
+		{"capella", &cfg.CAPELLA_FORK_EPOCH},
+		{"deneb", &cfg.DENEB_FORK_EPOCH},
+		{"electra", &cfg.ELECTRA_FORK_EPOCH},
+		{"fulu", &cfg.FULU_FORK_EPOCH},
+	} {
+		if fork.AtLeast(epoch.fork) {
+			*epoch.epoch = common.GENESIS_EPOCH
+		}
+	}
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, forkName+"_config.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGenesisForks(t *testing.T) {
+	testResourceDir := t.TempDir()
+	mnemonicsPath := filepath.Join(testResourceDir, "mnemonics.yaml")
+	mnemonicsData := []byte(`
+- mnemonic: "test test test test test test test test test test test junk"
+  count: 64
+`)
+	if err := os.WriteFile(mnemonicsPath, mnemonicsData, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, fork := range genesis.Forks {
+		t.Run(fork.Name, func(t *testing.T) {
+			// The config does not schedule any forks after the genesis fork,
+			// so neither may the execution-layer config.
+			elGenesis := testEth1Genesis()
+			cfg := elGenesis.Config
+			for _, f := range genesis.Forks {
+				if !f.AtLeast(fork.Name) || f == fork {
+					continue
+				}
+				switch f.Eth1ForkName {
+				case "shanghai":
+					cfg.ShanghaiTime = nil
+				case "cancun":
+					cfg.CancunTime = nil
+				case "prague":
+					cfg.PragueTime = nil
+				case "osaka":
+					cfg.OsakaTime = nil
+				}
+			}
+			elGenesisData, err := json.Marshal(elGenesis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			elGenesisPath := filepath.Join(testResourceDir, fork.Name+"_genesis.json")
+			if err := os.WriteFile(elGenesisPath, elGenesisData, 0755); err != nil {
+				t.Fatal(err)
+			}
+			outPath := filepath.Join(testResourceDir, fork.Name+".ssz")
+			c := &ForkGenesisCmd{
+				Fork: fork.Name,
+				SpecOptions: configs.SpecOptions{
+					Config:          writeTestConfig(t, testResourceDir, fork.Name),
+					Phase0Preset:    "minimal",
+					AltairPreset:    "minimal",
+					BellatrixPreset: "minimal",
+					CapellaPreset:   "minimal",
+					DenebPreset:     "minimal",
+					ElectraPreset:   "minimal",
+				},
+				Eth1Config:           elGenesisPath,
+				EthMatchGenesisTime:  true,
+				MnemonicsSrcFilePath: mnemonicsPath,
+				StateOutputPath:      outPath,
+				TranchesDir:          filepath.Join(testResourceDir, "tranches_"+fork.Name),
+			}
+			if err := c.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			stateData, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec, err := c.SpecOptions.Spec()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The fork is the same in every state: after the genesis time (8), validators root (32) and slot (8).
+			var previous, current common.Version
+			copy(previous[:], stateData[48:52])
+			copy(current[:], stateData[52:56])
+			expectedPrevious, expectedCurrent := fork.Versions(spec)
+			if previous != expectedPrevious || current != expectedCurrent {
+				t.Fatalf("unexpected fork versions %s, %s, expected %s, %s", previous, current, expectedPrevious, expectedCurrent)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/configs"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type InspectCmd struct {
+	configs.SpecOptions `ask:"."`
+	StatePath           string `ask:"--state" help:"Path to the SSZ genesis state file, of any fork"`
+	Format              string `ask:"--format" help:"Output format: text, yaml or json"`
+}
+
+func (g *InspectCmd) Help() string {
+	return "Print details of a genesis state, like the genesis validators root, fork digest and genesis block root"
+}
+
+func (g *InspectCmd) Default() {
+	g.SpecOptions.Default()
+	g.StatePath = "genesis.ssz"
+	g.Format = "text"
+}
+
+func (g *InspectCmd) Run(ctx context.Context, args ...string) error {
+	spec, err := g.SpecOptions.Spec()
+	if err != nil {
+		return err
+	}
+	stateData, err := os.ReadFile(g.StatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	state, fork, err := genesis.DecodeState(spec, stateData)
+	if err != nil {
+		return err
+	}
+	configYAML, err := specConfigYAML(&g.SpecOptions, spec)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	blobSchedule, err := genesis.ParseBlobSchedule(configYAML)
+	if err != nil {
+		return err
+	}
+	details, err := genesis.Inspect(spec, blobSchedule, fork, state)
+	if err != nil {
+		return err
+	}
+	return writeDetails(os.Stdout, g.Format, details)
+}
+
+func writeDetails(w io.Writer, format string, d *genesis.Details) error {
+	switch format {
+	case "text":
+		_, err := fmt.Fprintf(w, `fork: %s
+genesis_time: %d
+genesis_state_root: %s
+genesis_latest_block_header:
+  slot: %d
+  proposer_index: %d
+  parent_root: %s
+  state_root: %s
+  body_root: %s
+genesis_block_root_no_state_root: %s
+genesis_block_root_updated_state_root: %s
+genesis_validators_root: %s
+genesis_validators_count: %d
+genesis_active_validators_count: %d
+genesis_total_active_stake_gwei: %d
+genesis_total_balance_gwei: %d
+eth1_data:
+  deposit_root: %s
+  deposit_count: %d
+  block_hash: %s
+deposit index: %d
+genesis_fork_version: %s
+genesis_fork_digest: %s
+pre_genesis_fork_digest: %s
+`, d.Fork, d.GenesisTime, d.GenesisStateRoot,
+			d.GenesisLatestBlockHeader.Slot, d.GenesisLatestBlockHeader.ProposerIndex,
+			d.GenesisLatestBlockHeader.ParentRoot, d.GenesisLatestBlockHeader.StateRoot, d.GenesisLatestBlockHeader.BodyRoot,
+			d.GenesisBlockRootNoStateRoot, d.GenesisBlockRootUpdatedStateRoot,
+			d.GenesisValidatorsRoot, d.GenesisValidatorsCount, d.GenesisActiveValidatorsCount,
+			d.GenesisTotalActiveStakeGwei, d.GenesisTotalBalanceGwei,
+			d.Eth1Data.DepositRoot, d.Eth1Data.DepositCount, d.Eth1Data.BlockHash,
+			d.DepositIndex, d.GenesisForkVersion, d.GenesisForkDigest, d.PreGenesisForkDigest)
+		return err
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	default:
+		return fmt.Errorf("unknown output format %q, expected text, yaml or json", format)
+	}
+}
@@ -11,23 +11,31 @@ import (
 type GenesisCmd struct{}
 
 func (c *GenesisCmd) Help() string {
-	return "Create genesis state. See sub-commands for different fork versions."
+	return "Create genesis state. See the genesis sub-command, or the sub-commands for different fork versions."
 }
 
 func (c *GenesisCmd) Cmd(route string) (cmd interface{}, err error) {
 	switch route {
-	case "phase0":
-		cmd = &Phase0GenesisCmd{}
-	case "altair":
-		cmd = &AltairGenesisCmd{}
-	case "merge", "bellatrix":
-		cmd = &BellatrixGenesisCmd{}
-	case "capella":
-		cmd = &CapellaGenesisCmd{}
-	case "deneb":
-		cmd = &DenebGenesisCmd{}
-	case "electra":
-		cmd = &ElectraGenesisCmd{}
+	case "genesis":
+		cmd = &ForkGenesisCmd{}
+	case "phase0", "altair", "merge", "bellatrix", "capella", "deneb", "electra", "fulu":
+		cmd = &ForkGenesisCmd{Fork: route}
+	case "regenesis":
+		cmd = &RegenesisCmd{}
+	case "eth1-genesis":
+		cmd = &Eth1GenesisCmd{}
+	case "check-configs":
+		cmd = &CheckConfigsCmd{}
+	case "inspect":
+		cmd = &InspectCmd{}
+	case "convert":
+		cmd = &ConvertCmd{}
+	case "serve":
+		cmd = &ServeCmd{}
+	case "fork-schedule":
+		cmd = &ForkScheduleCmd{}
+	case "pubkey-cache":
+		cmd = &PubkeyCacheCmd{}
 	case "version":
 		cmd = &VersionCmd{}
 	default:
@@ -37,7 +45,7 @@ func (c *GenesisCmd) Cmd(route string) (cmd interface{}, err error) {
 }
 
 func (c *GenesisCmd) Routes() []string {
-	return []string{"phase0", "altair", "bellatrix", "capella", "deneb", "electra", "version"}
+	return []string{"genesis", "phase0", "altair", "bellatrix", "capella", "deneb", "electra", "fulu", "regenesis", "eth1-genesis", "check-configs", "inspect", "convert", "serve", "fork-schedule", "pubkey-cache", "version"}
 }
 
 func main() {
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type PubkeyCacheCmd struct {
+	MnemonicsSrcFilePath string             `ask:"--mnemonics" help:"File with YAML of key sources"`
+	PubkeyCacheDir       string             `ask:"--pubkey-cache-dir" help:"Directory of the pubkey cache"`
+	EthWithdrawalAddress common.Eth1Address `ask:"--eth1-withdrawal-address" help:"Eth1 Withdrawal address of the genesis validator set. Withdrawal pubkeys are only cached for validators with BLS withdrawal credentials"`
+	Verify               bool               `ask:"--verify" help:"Derive the cached pubkeys again and compare, instead of warming the cache. Mismatching pubkeys are removed from the cache"`
+}
+
+func (g *PubkeyCacheCmd) Help() string {
+	return "Warm the pubkey cache of the mnemonic validators, or verify the cached pubkeys"
+}
+
+func (g *PubkeyCacheCmd) Default() {
+	g.MnemonicsSrcFilePath = "mnemonics.yaml"
+	g.PubkeyCacheDir = "pubkey-cache"
+}
+
+func (g *PubkeyCacheCmd) Run(ctx context.Context, args ...string) error {
+	if g.Verify {
+		return genesis.VerifyPubkeyCache(g.MnemonicsSrcFilePath, g.PubkeyCacheDir, g.EthWithdrawalAddress, os.Stdout)
+	}
+	return genesis.WarmPubkeyCache(g.MnemonicsSrcFilePath, g.PubkeyCacheDir, g.EthWithdrawalAddress, os.Stdout)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type RegenesisCmd struct {
+	ForkGenesisCmd `ask:"."`
+	FromState      string `ask:"--from-state" help:"Path to the SSZ beacon state to take the validator registry from, of any fork, with the same preset"`
+	FromStateFork  string `ask:"--from-state-fork" help:"Fork of the --from-state state. Detected from the fork versions of the config if not set, which only works for a state of the same network"`
+	ReplaceIndices string `ask:"--replace-indices" help:"Comma-separated validator indices and ranges, like 0-99,200, to replace with the validators of the mnemonics and other validator sources, in order. The first validators if not set"`
+}
+
+func (g *RegenesisCmd) Help() string {
+	return "Create genesis state with the validator registry of an existing beacon state, e.g. for a consensus-layer shadow fork"
+}
+
+func (g *RegenesisCmd) Default() {
+	g.ForkGenesisCmd.Default()
+	g.FromState = ""
+	g.FromStateFork = ""
+	g.ReplaceIndices = ""
+}
+
+func (g *RegenesisCmd) Run(ctx context.Context, args ...string) error {
+	if g.FromState == "" {
+		return errors.New("no --from-state to take the validators from")
+	}
+	if g.Fork == "" {
+		if g.FromStateFork == "" {
+			return errors.New("no --fork for the genesis state, and no --from-state-fork to use instead")
+		}
+		g.Fork = g.FromStateFork
+	}
+	indices, err := genesis.ParseIndices(g.ReplaceIndices)
+	if err != nil {
+		return err
+	}
+	g.regenesis = &genesis.RegenesisSrc{
+		StatePath:      g.FromState,
+		StateFork:      g.FromStateFork,
+		ReplaceIndices: indices,
+	}
+	return g.ForkGenesisCmd.Run(ctx, args...)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+
+	"github.com/protolambda/eth2-testnet-genesis/genesis"
+)
+
+type ServeCmd struct {
+	ForkGenesisCmd `ask:"."`
+	StatePath      string `ask:"--state" help:"Path to the SSZ genesis state to serve, of any fork. If not set, the genesis state is built in memory from the genesis flags, without writing it"`
+	Addr           string `ask:"--addr" help:"Address to serve the Beacon API on"`
+}
+
+func (g *ServeCmd) Help() string {
+	return "Serve a genesis state, and the config, over the Beacon API endpoints for checkpoint sync and genesis state download"
+}
+
+func (g *ServeCmd) Default() {
+	g.ForkGenesisCmd.Default()
+	g.StatePath = ""
+	g.Addr = "127.0.0.1:5052"
+}
+
+func (g *ServeCmd) Run(ctx context.Context, args ...string) error {
+	var spec *common.Spec
+	var state common.BeaconState
+	var fork *genesis.Fork
+	if g.StatePath != "" {
+		var err error
+		spec, err = g.SpecOptions.Spec()
+		if err != nil {
+			return err
+		}
+		stateData, err := os.ReadFile(g.StatePath)
+		if err != nil {
+			return fmt.Errorf("failed to read state: %w", err)
+		}
+		state, fork, err = genesis.DecodeState(spec, stateData)
+		if err != nil {
+			return err
+		}
+	} else {
+		var res *genesis.Result
+		var err error
+		spec, _, res, err = g.build(ctx)
+		if err != nil {
+			return err
+		}
+		state, fork = res.State, res.Fork
+	}
+	handler, err := genesis.BeaconAPIHandler(spec, fork, state)
+	if err != nil {
+		return err
+	}
+	// Stop serving on ctx, or on an interrupt: the server shuts down gracefully, and in-flight downloads of the state can finish.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: g.Addr, Handler: handler}
+	shutdownErr := make(chan error, 1)
+	stopShutdown := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	})
+	defer stopShutdown()
+	fmt.Printf("serving %s genesis state on http://%s\n", fork.Name, g.Addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-shutdownErr
+}
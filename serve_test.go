@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestServeShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	c := &ServeCmd{
+		ForkGenesisCmd: ForkGenesisCmd{
+			Fork: "phase0",
+			SpecOptions: configs.SpecOptions{
+				Config:          "minimal",
+				Phase0Preset:    "minimal",
+				AltairPreset:    "minimal",
+				BellatrixPreset: "minimal",
+				CapellaPreset:   "minimal",
+				DenebPreset:     "minimal",
+				ElectraPreset:   "minimal",
+			},
+			Eth1BlockTimestamp: 1000,
+			InteropValidators:  64,
+			TranchesDir:        t.TempDir(),
+		},
+		Addr: addr,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx)
+	}()
+
+	// Wait for the server to serve the genesis, then stop it with the context.
+	for i := 0; ; i++ {
+		resp, err := http.Get("http://" + addr + "/eth/v1/beacon/genesis")
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("unexpected status %d", resp.StatusCode)
+			}
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("server stopped before serving: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		if i == 200 {
+			t.Fatal("server did not start")
+		}
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}